@@ -0,0 +1,126 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+var benchConfig = Config{
+	Origin:        []string{"https://app.example.com", "https://admin.example.com", "https://*.example.org"},
+	Headers:       []string{"Content-Type", "Authorization", "X-Request-Id"},
+	ExposeHeaders: []string{"X-Total-Count"},
+	Credentials:   true,
+}
+
+/**
+ * Decide on the request, only the middleware's own allocations are measured
+ */
+func benchmark(b *testing.B, r *http.Request) {
+	m := mustLoad(b, benchConfig)
+	ctx := &rest.Context{Request: r}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.evaluate(ctx)
+	}
+}
+
+func BenchmarkSimple(b *testing.B) {
+	benchmark(b, request("GET", "/", headerOrigin, "https://app.example.com"))
+}
+
+func BenchmarkPreflight(b *testing.B) {
+	benchmark(b, preflightRequest("/", "https://app.example.com", "PUT", "content-type, authorization"))
+}
+
+func BenchmarkRejection(b *testing.B) {
+	benchmark(b, request("GET", "/", headerOrigin, "https://evil.example.com"))
+}
+
+/**
+ * Allocations per decision, raise a budget only along with the change that needs it
+ */
+func TestAllocationBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are counted without the race detector")
+	}
+	m := mustLoad(t, benchConfig)
+	cases := []struct {
+		name   string
+		r      *http.Request
+		budget float64
+	}{
+		{"simple", request("GET", "/", headerOrigin, "https://app.example.com"), 7},
+		{"preflight", preflightRequest("/", "https://app.example.com", "PUT", "content-type, authorization"), 17},
+		{"rejection", request("GET", "/", headerOrigin, "https://evil.example.com"), 2},
+	}
+	for _, c := range cases {
+		ctx := &rest.Context{Request: c.r}
+		allocs := testing.AllocsPerRun(100, func() {
+			m.evaluate(ctx)
+		})
+		if allocs > c.budget {
+			t.Errorf("%s: %v allocs per decision, budget %v", c.name, allocs, c.budget)
+		}
+	}
+}
+
+func wildcardOrigins(n int) []string {
+	origins := make([]string, n)
+	for i := range origins {
+		origins[i] = fmt.Sprintf("https://*.tenant%d.example.com", i)
+	}
+	return origins
+}
+
+/**
+ * Matching costs O(labels) however many wildcard rules there are
+ */
+func BenchmarkWildcardRules(b *testing.B) {
+	for _, n := range []int{10, 500} {
+		m := newOriginMatcher(Config{Origin: wildcardOrigins(n)})
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.match("https://api.tenant5.example.com")
+				m.match("https://api.unknown.example.com")
+			}
+		})
+	}
+}
+
+/**
+ * A few origins over and over, their headers come from the cache
+ */
+func BenchmarkRepeatingOrigins(b *testing.B) {
+	m := mustLoad(b, benchConfig)
+	var ctxs []*rest.Context
+	for _, origin := range []string{"https://app.example.com", "https://admin.example.com", "https://shop.example.org"} {
+		ctxs = append(ctxs, &rest.Context{Request: request("GET", "/", headerOrigin, origin)})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.evaluate(ctxs[i%len(ctxs)])
+	}
+}
+
+/**
+ * Preflight answered by `PreflightHandler`, against `BenchmarkPreflight` through `Load`
+ */
+func BenchmarkPreflightHandler(b *testing.B) {
+	m := mustNew(benchConfig, modePreflight)
+	ctx := &rest.Context{Request: preflightRequest("/", "https://app.example.com", "PUT", "content-type, authorization")}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.evaluate(ctx)
+	}
+}
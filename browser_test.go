@@ -0,0 +1,107 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+/**
+ * Requests as browsers send them, see "Browser request sequences" of the README.
+ * A rejection is thrown, the error middleware ends the response.
+ */
+func TestBrowserSequences(t *testing.T) {
+	config := Config{Origin: []string{"https://app.com"}, ExposeHeaders: []string{"X-Total-Count"}}
+	credentials := config
+	credentials.Credentials = true
+
+	simple := http.Header{
+		headerAllowOrigin:   {"https://app.com"},
+		headerExposeHeaders: {"X-Total-Count"},
+		headerVary:          {headerOrigin},
+	}
+	preflight := http.Header{
+		headerAllowOrigin:  {"https://app.com"},
+		headerAllowMethods: {"GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH"},
+		headerAllowHeaders: {"Content-Type"},
+		headerMaxAge:       {"3600"},
+		headerVary:         {"Origin, Access-Control-Request-Method, Access-Control-Request-Headers"},
+	}
+	rejectedPreflight := http.Header{
+		headerAllowOrigin: {"https://app.com"},
+		headerVary:        {"Origin, Access-Control-Request-Method, Access-Control-Request-Headers"},
+	}
+	withCredentials := func(h http.Header) http.Header {
+		out := h.Clone()
+		out.Set(headerAllowCredentials, "true")
+		return out
+	}
+
+	type step struct {
+		name    string
+		r       *http.Request
+		status  int
+		ended   bool
+		err     error
+		headers http.Header
+	}
+	sequences := []struct {
+		name   string
+		config Config
+		steps  []step
+	}{
+		{"get", config, []step{
+			{"GET", request("GET", "/items", headerOrigin, "https://app.com"), 0, false, nil, simple},
+		}},
+		{"json post", config, []step{
+			{"preflight", preflightRequest("/items", "https://app.com", "POST", "content-type"), 204, true, nil, preflight},
+			{"POST", request("POST", "/items", headerOrigin, "https://app.com", "Content-Type", "application/json"), 0, false, nil, simple},
+		}},
+		{"credentialed", credentials, []step{
+			{"preflight", preflightRequest("/items", "https://app.com", "POST", "content-type"), 204, true, nil, withCredentials(preflight)},
+			{"GET", request("GET", "/items", headerOrigin, "https://app.com", "Cookie", "session=1"), 0, false, nil, withCredentials(simple)},
+		}},
+		{"disallowed origin", config, []step{
+			{"GET", request("GET", "/items", headerOrigin, "https://evil.com"), 403, false, OriginNotAllowed, http.Header{}},
+		}},
+		{"disallowed method", config, []step{
+			{"preflight", preflightRequest("/items", "https://app.com", "TRACE", ""), 403, false, MethodNotAllowed, rejectedPreflight},
+		}},
+		{"disallowed header", config, []step{
+			{"preflight", preflightRequest("/items", "https://app.com", "GET", "x-secret"), 403, false, HeadersNotAllowed, rejectedPreflight},
+		}},
+	}
+
+	for _, seq := range sequences {
+		m := mustLoad(t, seq.config)
+		for _, s := range seq.steps {
+			w := do(m, s.r)
+			if w.status != s.status || w.ended != s.ended || w.err != s.err {
+				t.Errorf("%s, %s: status = %d, ended = %v, err = %v; want %d, %v, %v",
+					seq.name, s.name, w.status, w.ended, w.err, s.status, s.ended, s.err)
+			}
+			if !reflect.DeepEqual(w.header, s.headers) {
+				t.Errorf("%s, %s: headers = %v, want %v", seq.name, s.name, w.header, s.headers)
+			}
+		}
+	}
+}
+
+func TestHeaderNamesCaseInsensitive(t *testing.T) {
+	m := mustLoad(t, Config{Headers: []string{"Content-Type", "X-Request-Id"}})
+
+	w := do(m, preflightRequest("/", "https://app.com", "PUT", "content-type, x-request-id"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type, X-Request-Id")
+
+	lenient := mustLoad(t, Config{Headers: []string{"Content-Type"}, LenientHeaders: true})
+	w = do(lenient, preflightRequest("/", "https://app.com", "PUT", "x-trace, content-type"))
+	expectHeader(t, w.header, headerAllowHeaders, "content-type")
+}
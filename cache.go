@@ -0,0 +1,119 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"container/list"
+	"sync"
+)
+
+/**
+ * Header name and value pair
+ */
+type header [2]string
+
+/**
+ * Bounded LRU cache of headers computed for an allowed origin, and the rule which allowed it
+ *
+ * A nil cache is valid and never stores anything.
+ */
+type originCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	origin  string
+	headers []header
+	source  MatchSource
+}
+
+func newOriginCache(size int) *originCache {
+	if size <= 0 {
+		return nil
+	}
+	return &originCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *originCache) get(origin string) ([]header, MatchSource, bool) {
+	if c == nil {
+		return nil, MatchNone, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[origin]
+	if !ok {
+		return nil, MatchNone, false
+	}
+	c.ll.MoveToFront(e)
+	entry := e.Value.(*cacheEntry)
+	return entry.headers, entry.source, true
+}
+
+func (c *originCache) add(origin string, headers []header, source MatchSource) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[origin]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*cacheEntry).headers = headers
+		e.Value.(*cacheEntry).source = source
+		return
+	}
+	c.items[origin] = c.ll.PushFront(&cacheEntry{origin: origin, headers: headers, source: source})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).origin)
+	}
+}
+
+// most `ContextOrigins` lists compiled, further ones are compiled per request
+const routeMatchersSize = 1024
+
+var noOrigins = newOriginMatcher(Config{})
+
+/**
+ * Matchers compiled from `ContextOrigins` lists, keyed by slice identity: a router sets the same
+ * slice on every request of a route, so it is compiled once
+ */
+type routeMatchers struct {
+	mu    sync.RWMutex
+	items map[routeKey]*originMatcher
+}
+
+type routeKey struct {
+	first *string
+	n     int
+}
+
+func (c *routeMatchers) get(list []string) *originMatcher {
+	if len(list) == 0 {
+		return noOrigins
+	}
+	key := routeKey{&list[0], len(list)}
+	c.mu.RLock()
+	m, ok := c.items[key]
+	c.mu.RUnlock()
+	if ok {
+		return m
+	}
+	m = newOriginMatcher(Config{Origin: list})
+	c.mu.Lock()
+	if len(c.items) < routeMatchersSize {
+		c.items[key] = m
+	}
+	c.mu.Unlock()
+	return m
+}
@@ -0,0 +1,52 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+)
+
+func TestOriginCache(t *testing.T) {
+	c := newOriginCache(2)
+	c.add("https://a.com", []header{{headerAllowOrigin, "https://a.com"}}, MatchExact)
+	c.add("https://b.com", nil, MatchWildcard)
+	c.get("https://a.com")
+	// b is the least recently used one
+	c.add("https://c.com", nil, MatchGlob)
+	if _, _, ok := c.get("https://b.com"); ok {
+		t.Error("least recently used entry kept")
+	}
+	headers, source, ok := c.get("https://a.com")
+	if !ok || source != MatchExact || len(headers) != 1 {
+		t.Errorf("a: %v, %v, %v", headers, source, ok)
+	}
+	if c.ll.Len() != 2 || len(c.items) != 2 {
+		t.Errorf("%d entries, bound 2", c.ll.Len())
+	}
+
+	var none *originCache
+	none.add("https://a.com", nil, MatchExact)
+	if _, _, ok := none.get("https://a.com"); ok || newOriginCache(-1) != nil {
+		t.Error("disabled cache stores entries")
+	}
+}
+
+func TestOriginCacheBypass(t *testing.T) {
+	if m := mustLoad(t, Config{}); m.cache == nil {
+		t.Error("no cache by default")
+	}
+	allowed := true
+	m := mustLoad(t, Config{Origin: []string{}, AllowOriginFunc: func(string) bool { return allowed }})
+	if m.cache != nil {
+		t.Error("cache with AllowOriginFunc")
+	}
+	do(m, request("GET", "/", headerOrigin, "https://a.com"))
+	allowed = false
+	// the func decides anew on every request
+	if w := do(m, request("GET", "/", headerOrigin, "https://a.com")); w.err != OriginNotAllowed {
+		t.Errorf("err = %v", w.err)
+	}
+}
@@ -0,0 +1,80 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+var unauthorized = errors.New("UNAUTHORIZED")
+
+/**
+ * Router composed as documented, `api.Use(logger)`, `api.Use(cors.Load(config))`, `api.Use(auth)`
+ * and a route, recording the handlers each request ran through
+ */
+func composed(config Config) (*rest.API, *[]string) {
+	var api rest.API
+	ran := new([]string)
+	step := func(name string, h rest.Handler) rest.Handler {
+		return func(ctx *rest.Context) {
+			*ran = append(*ran, name)
+			h(ctx)
+		}
+	}
+	api.Use(step("logger", func(ctx *rest.Context) {}))
+	api.Use(step("cors", Load(config)))
+	api.Use(step("auth", func(ctx *rest.Context) {
+		if ctx.Request.Header.Get("Authorization") == "" {
+			ctx.Status(401)
+			ctx.Throw(unauthorized)
+		}
+	}))
+	api.Get("/user", step("route", func(ctx *rest.Context) { ctx.Text("profile") }))
+	for _, err := range []error{unauthorized, OriginNotAllowed} {
+		api.Exception(err.Error(), func(ctx *rest.Context) { ctx.Text(ctx.GetError().Error()) })
+	}
+	return &api, ran
+}
+
+func TestBeforeAuth(t *testing.T) {
+	api, ran := composed(Config{Origin: []string{"https://app.com"}, Credentials: true, Headers: []string{"Authorization"}})
+	send := func(r *http.Request) *http.Response {
+		*ran = nil
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, r)
+		return rec.Result()
+	}
+
+	// browsers send preflights without credentials, they never reach auth
+	res := send(preflightRequest("/user", "https://app.com", "GET", "authorization"))
+	if res.StatusCode != 204 || len(*ran) != 2 {
+		t.Errorf("preflight: status = %d, ran %v", res.StatusCode, *ran)
+	}
+	expectHeader(t, res.Header, headerAllowHeaders, "Authorization")
+
+	res = send(request("GET", "/user", headerOrigin, "https://app.com"))
+	if res.StatusCode != 401 || len(*ran) != 3 {
+		t.Errorf("unauthenticated: status = %d, ran %v", res.StatusCode, *ran)
+	}
+	// the browser can read the 401, as the CORS headers are set already
+	expectHeader(t, res.Header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, res.Header, headerAllowCredentials, "true")
+
+	res = send(request("GET", "/user", headerOrigin, "https://app.com", "Authorization", "Bearer t"))
+	if res.StatusCode != 200 || len(*ran) != 4 {
+		t.Errorf("authenticated: status = %d, ran %v", res.StatusCode, *ran)
+	}
+
+	res = send(request("GET", "/user", headerOrigin, "https://evil.com", "Authorization", "Bearer t"))
+	if res.StatusCode != 403 || len(*ran) != 2 {
+		t.Errorf("rejected origin: status = %d, ran %v", res.StatusCode, *ran)
+	}
+}
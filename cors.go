@@ -9,6 +9,13 @@ package cors
 // Reference to: https://fetch.spec.whatwg.org/#http-cors-protocol
 import (
 	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/textproto"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +27,18 @@ var (
 	OriginNotAllowed  = errors.New("ORIGIN_NOT_ALLOWED")
 	HeadersNotAllowed = errors.New("HEADERS_NOT_ALLOWED")
 	MethodNotAllowed  = errors.New("METHOD_NOT_ALLOWED")
+	OriginRequired    = errors.New("ORIGIN_REQUIRED")
+	InsecureRequest   = errors.New("INSECURE_REQUEST")
+
+	MalformedPreflight = errors.New("MALFORMED_PREFLIGHT")
+
+	InvalidResourcePolicy = errors.New("INVALID_RESOURCE_POLICY")
+	InvalidOpenerPolicy   = errors.New("INVALID_OPENER_POLICY")
+	InvalidEmbedderPolicy = errors.New("INVALID_EMBEDDER_POLICY")
+	ForbiddenHeader       = errors.New("FORBIDDEN_HEADER")
+
+	WildcardWithCredentials = errors.New("WILDCARD_WITH_CREDENTIALS")
+	InvalidCredentialPath   = errors.New("INVALID_CREDENTIAL_PATH")
 )
 
 /**
@@ -60,14 +79,232 @@ type Config struct {
 	ExposeHeaders []string
 	Credentials   bool
 	MaxAge        time.Duration
+
+	// Shorten `Access-Control-Allow-Methods` when the methods are the default full set: `*`,
+	// or the requested method alone with credentials, as browsers take `*` literally then
+	OmitDefaultMethodsHeader bool
+
+	// Methods emitted in `Access-Control-Allow-Methods` instead of the enforced `Methods`,
+	// e.g. a broader documented list. Defaults to `Methods`. Rules and origin policies
+	// setting their own `Methods` always advertise those.
+	AdvertisedMethods []string
+
+	// Exposed headers used instead of `ExposeHeaders: ["*"]` on credentialed responses
+	ExposeHeadersList []string
+
+	// CORS-safelisted response headers are exposed anyway: drop them from the emitted
+	// expose headers and warn about listing them
+	ExposeSafelistedHeaders bool
+
+	// With credentials, `ExposeHeaders: ["*"]` and no `ExposeHeadersList`, expose the headers
+	// actually present on the response, computed when the handlers commit it
+	NegotiateExposeHeaders bool
+
+	// Per request exposed headers, replaces `ExposeHeaders` when set,
+	// e.g. to expose `X-Total-Count` on paginated routes only
+	ExposeHeadersFunc func(ctx *rest.Context) []string
+
+	// With `Credentials`, emit `Access-Control-Allow-Credentials` only on these request paths.
+	// Entries are prefixes, or `path.Match` patterns when they contain `*`, `?` or `[`.
+	CredentialPaths []string
+
+	// Emit credentials for origins allowed by a wildcard or `scheme://host:*` `Origin` entry,
+	// `OriginGlobs`, `OriginPatterns` or `OriginRegexes` as well. Such rules cover many origins,
+	// so by default their origins are reflected without credentials.
+	AllowCredentialsForPatterns bool
+
+	// Emit `Access-Control-Allow-Credentials` for `https` origins only; `http`, `null` and other
+	// origins are still allowed, without credentials
+	CredentialsRequireHTTPS bool
+
+	// `Cache-Control` of successful preflight responses, e.g. "public, max-age=600" for
+	// intermediary caches. Unset emits none.
+	PreflightCacheControl string
+
+	// Per request preflight max age, overrides `MaxAge` when set
+	MaxAgeFunc func(ctx *rest.Context) time.Duration
+
+	// Server origin matched by a `self` entry of `Origin` and checked by `CheckSecFetchSite`,
+	// derived from the request's TLS state and `Host` when empty. Set it behind a proxy, which changes both.
+	SelfOrigin string
+
+	// Additional origin matchers, evaluated after `Origin` in this order
+	OriginGlobs     []string
+	OriginPatterns  []*regexp.Regexp
+	OriginRegexes   []OriginRegexWithValidator
+	AllowOriginFunc func(origin string) bool
+
+	// Allow origins whose host is a DNS or IP SAN of the verified client certificate.
+	// Requires mutual TLS terminated by this server with `tls.RequireAndVerifyClientCert`
+	// or `tls.VerifyClientCertIfGiven`, as `r.TLS.VerifiedChains` must be set.
+	AllowByClientCertSAN bool
+
+	// Last allow path, e.g. for a signed token authorizing an embed out-of-band
+	TokenValidator func(ctx *rest.Context, origin string) bool
+
+	// Remote allowlist asked after the matchers above, decisions are cached for `ResolverTTL`,
+	// at most `OriginCacheSize` of them, 1024 when that is negative.
+	// Resolver errors reject the origin unless `ResolverFailOpen` is set.
+	OriginResolver   OriginResolver
+	ResolverTTL      time.Duration
+	ResolverFailOpen bool
+
+	// Rewrite the allowed origin before it is reflected, an empty result omits the header
+	OriginRewrite func(origin string) string
+
+	// Max number of allowed origins whose response headers are cached, negative disables caching.
+	// The cache is bypassed when `AllowOriginFunc` or `OriginResolver` is set, as their decision
+	// may change over time.
+	OriginCacheSize int
+
+	// Longer origins are rejected before matching, negative disables the check.
+	// Defaults to the longest DNS name with scheme and port.
+	MaxOriginLength int
+
+	// Preflights requesting more headers are rejected before any header is checked,
+	// negative disables the check
+	MaxRequestHeaders int
+
+	// Apply CORS only to request paths with one of these prefixes, others pass through untouched
+	PathPrefixes []string
+
+	// Origin entries per request `Host`, with or without port, replacing all origin matchers
+	// for that host. Other hosts use the global matchers.
+	OriginsByHost map[string][]string
+
+	// Settings for request path prefixes, the first matching rule wins
+	Rules []Rule
+
+	// Narrower settings for specific origins, the first matching policy wins
+	OriginPolicies []OriginPolicy
+
+	// Allowed origins also granted `Access-Control-Allow-Private-Network` on preflights asking
+	// for it (Private Network Access), supports exact and wildcard entries. Defaults to none.
+	PrivateNetworkOrigins []string
+
+	// Origins rejected even when allowed by the matchers above, supports exact and wildcard entries
+	DenyOrigin []string
+
+	// Emit `Access-Control-Allow-Origin: *` even when the request carries no `Origin` header,
+	// provided all origins are allowed. Useful for fonts and static assets.
+	AlwaysSetAllowOrigin bool
+
+	// Non-standard: emit the exact origins allowed for the request comma joined in
+	// `Access-Control-Allow-Origin` for legacy machine clients, those of the matching rule or host
+	// if any. `*`, `self` and pattern entries are left out. Browsers reject such a value, so this
+	// breaks browser CORS; it is skipped for requests carrying `Sec-Fetch-Mode`.
+	ListAllowOrigin bool
+
+	// Reject CORS requests not served over TLS by this server, whatever their origin.
+	// Behind a TLS terminating proxy `r.TLS` is never set, so every CORS request is rejected.
+	RequireTLS bool
+
+	// Reject requests without `Origin` header instead of passing them through
+	RequireOrigin bool
+
+	// Behave strictly per the Fetch spec. It enables all of the following at once:
+	//  - never emit `*` together with credentials
+	//  - reject the forbidden methods `CONNECT`, `TRACE` and `TRACK` in preflight
+	//  - treat `OPTIONS` without `Access-Control-Request-Method` as a non-preflight request,
+	//    whatever `BareOptionsBehavior` says
+	//  - fail validation on forbidden request header names in `Headers`
+	SpecCompliant bool
+
+	// Handling of `*` origin with credentials, defaults to `WildcardCredentialsReflect`
+	UnsafeWildcardCredentials WildcardCredentials
+
+	// Handling of `OPTIONS` without `Access-Control-Request-Method`, defaults to `BareOptionsSimple`
+	BareOptionsBehavior BareOptions
+
+	// Add diagnostic headers, like `X-Cors-Maxage-Seconds`, to help troubleshooting preflight caching.
+	DebugHeaders bool
+
+	// Never reject: rejections are passed to `ReportFunc` (and logged as such by `StructuredLogger`)
+	// while the request is answered as if allowed, to try out a stricter policy safely
+	ReportOnly bool
+	ReportFunc func(v Violation)
+
+	// Reject with a plain 403 response instead of `ctx.Throw`, bypassing the error middleware
+	SilentReject bool
+
+	// Continue the chain without CORS headers for a disallowed origin instead of a 403,
+	// so the response stays the one the app would give and the browser blocks it
+	SoftReject bool
+
+	// Add `X-Cors-Reason` naming the failed check to rejections, for client side diagnostics
+	RejectReasonHeader bool
+
+	// Check the method of non-preflight requests against `Methods` as well,
+	// except for `SimpleMethods` which never need a preflight
+	EnforceMethodOnSimpleRequest bool
+	SimpleMethods                []string
+
+	// Add `Authorization` to the allowed headers, including those of `OriginPolicies` and
+	// `Rules`, when `Credentials` is set
+	AutoAllowAuthorization bool
+
+	// Allow and echo whatever headers a preflight requests, `Headers` is ignored. Any header
+	// then reaches the app from every allowed origin, e.g. ones trusted by a proxy.
+	AllowRequestedHeaders bool
+
+	// Never reject a preflight for its requested headers, only the allowed ones among them
+	// are echoed in `Access-Control-Allow-Headers`
+	LenientHeaders bool
+
+	// Emit reflected request header names in canonical form (`X-Custom`) instead of as received
+	CanonicalizeReflectedHeaders bool
+
+	// Emit allowed methods and headers as one header line per value, for proxies
+	// that mishandle long comma joined values
+	SplitHeaderLines bool
+
+	// Also emit the standard `Allow` header on preflight responses, listing the allowed
+	// (or advertised) methods even when `Access-Control-Allow-Methods` is shortened to `*`
+	SetAllowHeader bool
+
+	// Let a successful preflight continue to the next handlers, with its headers set, when it
+	// returns true; e.g. for an authenticated `OPTIONS` the app answers itself
+	PassPreflightWhen func(ctx *rest.Context) bool
+
+	// Keep the status set by earlier handlers on a successful preflight instead of 204. Only a
+	// custom `StatusResponder` passed to `Respond` can tell that status; `Load` and `Handler`
+	// read none from `*rest.Context` and always answer 204.
+	PreserveStatus bool
+
+	// Reject requests whose `Sec-Fetch-Site` claims same-origin/same-site while `Origin`
+	// disagrees with the server origin. This is a heuristic, see `secFetchConsistent`.
+	CheckSecFetchSite bool
+
+	// Emit `Cross-Origin-Resource-Policy` on all responses: "same-site", "same-origin" or "cross-origin"
+	ResourcePolicy string
+
+	// Emit `Cross-Origin-Opener-Policy` and `Cross-Origin-Embedder-Policy` on all responses
+	OpenerPolicy   string
+	EmbedderPolicy string
+
+	// Receives warnings about a suspicious config, defaults to the standard logger
+	Logger *log.Logger
+
+	// Receives structured events (origin, method, decision, reason, status) for every request,
+	// and config warnings unless `Logger` is set
+	StructuredLogger *slog.Logger
 }
 
+// Reference to: https://fetch.spec.whatwg.org/#forbidden-method
+var forbiddenMethods = []string{"CONNECT", "TRACE", "TRACK"}
+
 var _config = Config{
 	Origin:      []string{"*"},
 	Methods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD", "PATCH"},
 	Headers:     []string{"Content-Type"},
 	Credentials: false,
 	MaxAge:      time.Hour,
+
+	SimpleMethods:     []string{"GET", "HEAD", "POST"},
+	OriginCacheSize:   1024,
+	ResolverTTL:       time.Minute,
+	MaxOriginLength:   253 + len("https://") + len(":65535"),
+	MaxRequestHeaders: 64,
 }
 
 /**
@@ -86,6 +323,83 @@ func merge(source Config, target *Config) {
 	if target.MaxAge == 0 {
 		target.MaxAge = source.MaxAge
 	}
+	if target.SimpleMethods == nil {
+		target.SimpleMethods = source.SimpleMethods
+	}
+	if target.OriginCacheSize == 0 {
+		target.OriginCacheSize = source.OriginCacheSize
+	}
+	if target.ResolverTTL == 0 {
+		target.ResolverTTL = source.ResolverTTL
+	}
+	if target.MaxOriginLength == 0 {
+		target.MaxOriginLength = source.MaxOriginLength
+	}
+	if target.MaxRequestHeaders == 0 {
+		target.MaxRequestHeaders = source.MaxRequestHeaders
+	}
+}
+
+/**
+ * Copy slices of config, so the handler never shares memory with the caller
+ */
+func clone(config Config) Config {
+	config.Origin = copySlice(config.Origin)
+	config.Methods = copySlice(config.Methods)
+	config.Headers = copySlice(config.Headers)
+	config.ExposeHeaders = copySlice(config.ExposeHeaders)
+	config.ExposeHeadersList = copySlice(config.ExposeHeadersList)
+	config.DenyOrigin = copySlice(config.DenyOrigin)
+	config.SimpleMethods = copySlice(config.SimpleMethods)
+	config.AdvertisedMethods = copySlice(config.AdvertisedMethods)
+	config.OriginGlobs = copySlice(config.OriginGlobs)
+	config.OriginRegexes = append([]OriginRegexWithValidator(nil), config.OriginRegexes...)
+	config.PathPrefixes = copySlice(config.PathPrefixes)
+	config.CredentialPaths = copySlice(config.CredentialPaths)
+	config.PrivateNetworkOrigins = copySlice(config.PrivateNetworkOrigins)
+	if config.OriginsByHost != nil {
+		hosts := make(map[string][]string, len(config.OriginsByHost))
+		for host, list := range config.OriginsByHost {
+			hosts[host] = copySlice(list)
+		}
+		config.OriginsByHost = hosts
+	}
+	if config.OriginPolicies != nil {
+		policies := make([]OriginPolicy, len(config.OriginPolicies))
+		for i, pol := range config.OriginPolicies {
+			policies[i] = copyPolicy(pol)
+		}
+		config.OriginPolicies = policies
+	}
+	if config.Rules != nil {
+		rules := make([]Rule, len(config.Rules))
+		for i, rl := range config.Rules {
+			rules[i] = Rule{PathPrefix: rl.PathPrefix, OriginPolicy: copyPolicy(rl.OriginPolicy)}
+		}
+		config.Rules = rules
+	}
+	if config.OriginPatterns != nil {
+		config.OriginPatterns = append([]*regexp.Regexp(nil), config.OriginPatterns...)
+	}
+	return config
+}
+
+func copyPolicy(pol OriginPolicy) OriginPolicy {
+	return OriginPolicy{
+		Origin:  copySlice(pol.Origin),
+		Methods: copySlice(pol.Methods),
+		Headers: copySlice(pol.Headers),
+		MaxAge:  pol.MaxAge,
+	}
+}
+
+func copySlice(data []string) []string {
+	if data == nil {
+		return nil
+	}
+	out := make([]string, len(data))
+	copy(out, data)
+	return out
 }
 
 /**
@@ -100,28 +414,522 @@ func hasMatch(data []string, str string) bool {
 	return false
 }
 
+/**
+ * Search string in slice, case-insensitively as for header names
+ */
+func hasFold(data []string, str string) bool {
+	for _, v := range data {
+		if strings.EqualFold(v, str) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Add `Authorization` to header list, unless listed already or covered by `*`
+ */
+func withAuthorization(headers []string) []string {
+	if hasMatch(headers, "*") || hasFold(headers, "Authorization") {
+		return headers
+	}
+	return append(headers, "Authorization")
+}
+
+/**
+ * Drop CORS-safelisted response header names
+ */
+func withoutSafelisted(headers []string) []string {
+	if headers == nil {
+		return nil
+	}
+	out := make([]string, 0, len(headers))
+	for _, h := range headers {
+		if !hasMatch(safelistedResponseHeaders, strings.ToLower(h)) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+/**
+ * Drop `*` values, which only mean a wildcard without credentials
+ */
+func withoutWildcard(data []string) []string {
+	out := make([]string, 0, len(data))
+	for _, v := range data {
+		if v != "*" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+/**
+ * String starts with any of the prefixes
+ */
+func hasPrefix(prefixes []string, str string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(str, p) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Path has one of the prefixes, or matches one of the `path.Match` patterns
+ */
+func matchPath(paths []string, str string) bool {
+	for _, p := range paths {
+		if !strings.ContainsAny(p, "*?[") {
+			if strings.HasPrefix(str, p) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, str); ok {
+			return true
+		}
+	}
+	return false
+}
+
 /**
  * Value should be included
  */
 func hasInclude(data []string, val []string) bool {
-	out := make(map[string]bool)
-	if len(data) < len(val) {
-		return false
+	// lists are short, a scan beats building a set per request
+	for _, v := range val {
+		if !hasMatch(data, v) {
+			return false
+		}
 	}
+	return true
+}
 
-	for _, d := range data {
-		out[d] = true
-	}
+/**
+ * Both lists hold the same values, in any order
+ */
+func sameSet(a []string, b []string) bool {
+	return hasInclude(a, b) && hasInclude(b, a)
+}
 
+/**
+ * Every value is included, case-insensitively as for header names
+ */
+func hasIncludeFold(data []string, val []string) bool {
 	for _, v := range val {
-		if !out[v] {
+		if !hasFold(data, v) {
 			return false
 		}
 	}
+	return true
+}
 
+/**
+ * Values of data also found in allowed case-insensitively, in the order and casing of data
+ */
+func intersect(data []string, allowed []string) []string {
+	var out []string
+	for _, v := range data {
+		if hasFold(allowed, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+/**
+ * Canonical form of header names, `x-custom` gives `X-Custom`
+ */
+func canonical(data []string) []string {
+	out := make([]string, len(data))
+	for i, v := range data {
+		out[i] = textproto.CanonicalMIMEHeaderKey(v)
+	}
+	return out
+}
+
+/**
+ * Valid HTTP token, as used for method and header names
+ */
+func isToken(str string) bool {
+	if str == "" {
+		return false
+	}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
 	return true
 }
 
+/**
+ * Split comma separated header value, trim spaces and drop empty entries
+ */
+func parseList(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+/**
+ * Set list header, either comma joined or one header line per value
+ */
+func setList(h http.Header, config Config, name string, values []string) {
+	if config.SplitHeaderLines {
+		for _, v := range values {
+			h.Add(name, v)
+		}
+		return
+	}
+	h.Set(name, strings.Join(values, ", "))
+}
+
+/**
+ * CORS decision for a request, it can be applied to any response
+ */
+type Result struct {
+	// Headers to set on the response
+	Headers http.Header
+	// 204 for a handled preflight, 403 on rejection, 400 on a malformed preflight, otherwise 0
+	Status int
+	// The response ends here, the request must not reach the next handlers
+	End bool
+	// Request headers which influenced the response, also set as `Vary` in `Headers`
+	Vary []string
+	// Rule which allowed the origin, `MatchNone` when no origin was allowed
+	Source MatchSource
+
+	// expose headers are computed from the actual response, see `exposeWriter`
+	negotiate bool
+	// first rejection a report-only policy carried on from, see `deny`
+	violation error
+}
+
+func (res *Result) vary(names ...string) {
+	res.Vary = append(res.Vary, names...)
+	res.Headers.Set(headerVary, strings.Join(res.Vary, ", "))
+}
+
+func (res Result) reject(err error) (Result, error) {
+	return res.fail(403, err)
+}
+
+func (res Result) fail(status int, err error) (Result, error) {
+	res.Status = status
+	res.End = true
+	return res, err
+}
+
+/**
+ * Rejection stands, unless a report-only policy records it and carries on as if allowed
+ */
+func (m *Middleware) deny(res *Result, err error) bool {
+	if !m.config.ReportOnly {
+		return true
+	}
+	if res.violation == nil {
+		res.violation = err
+	}
+	return false
+}
+
+/**
+ * Handling of a plain `OPTIONS` request which carries no `Access-Control-Request-Method`,
+ * so it is not a preflight
+ */
+type BareOptions int
+
+const (
+	// Treat it as a regular CORS request: set allow-origin and continue
+	BareOptionsSimple BareOptions = iota
+	// Answer it with 204 and the allow-origin headers as a courtesy
+	BareOptionsRespond
+	// Pass it to the next handlers untouched by CORS
+	BareOptionsPassThrough
+)
+
+/**
+ * Handling of `Origin: ["*"]` together with `Credentials`, browsers never accept
+ * `Access-Control-Allow-Origin: *` on a credentialed response
+ *
+ * A `*` entry of a rule, `OriginsByHost` or `ContextOrigins` is handled alike, except that
+ * by default its origins get no credentials: such lists rarely mean to grant them to every origin.
+ * As `ContextOrigins` is only known per request, `WildcardCredentialsPanic` rejects those instead.
+ */
+type WildcardCredentials int
+
+const (
+	// Reflect the concrete request origin, so every origin gets credentials from global `Origin`
+	WildcardCredentialsReflect WildcardCredentials = iota
+	// Fail validation with `WILDCARD_WITH_CREDENTIALS`, so `Load` panics at startup
+	WildcardCredentialsPanic
+	// Reject every request with an `Origin` header at runtime, with `ORIGIN_NOT_ALLOWED`
+	WildcardCredentialsReject
+)
+
+/**
+ * Origin pattern whose named captures must pass `Validate` as well, e.g. to bound
+ * the number of `^https://pr-(?P<pr>[0-9]+)\.preview\.example\.com$`
+ */
+type OriginRegexWithValidator struct {
+	Pattern  *regexp.Regexp
+	Validate func(captures map[string]string) bool
+}
+
+/**
+ * Per-origin policy, narrows the global config for the listed origins
+ *
+ * `Origin` accepts exact and wildcard entries. A policy never allows an origin by itself,
+ * the origin must still pass the global matchers. The first matching policy wins, its
+ * unset (nil or zero) fields inherit from the global config. `MaxAgeFunc` still overrides `MaxAge`.
+ */
+type OriginPolicy struct {
+	Origin  []string
+	Methods []string
+	Headers []string
+	MaxAge  time.Duration
+}
+
+type originPolicy struct {
+	matcher *originMatcher
+	OriginPolicy
+}
+
+/**
+ * Settings for request paths with a prefix, e.g. any origin for `GET` on `/api/public`
+ *
+ * A nil `Origin` keeps the global origin matchers, otherwise the listed entries
+ * (exact, wildcard, `*` or `self`) replace all of them. Unset `Methods` and `Headers`
+ * inherit from the global config, `OriginPolicies` still narrow them per origin.
+ */
+type Rule struct {
+	PathPrefix string
+	OriginPolicy
+}
+
+type rule struct {
+	prefix string
+	originPolicy
+}
+
+/**
+ * CORS middleware compiled once from a config, shared by `Load` and `Evaluate`
+ */
+type Middleware struct {
+	config   Config
+	origins  *originMatcher
+	denied   *originMatcher
+	cache    *originCache
+	resolver *cachedResolver
+	policies []originPolicy
+	rules    []rule
+	hosts    map[string]*originMatcher
+	// nil without `PrivateNetworkOrigins`
+	privateNetwork *originMatcher
+	routes         *routeMatchers
+	mode           mode
+}
+
+/**
+ * Which requests the middleware handles
+ */
+type mode int
+
+const (
+	modeAll mode = iota
+	modePreflight
+	modeSimple
+)
+
+/**
+ * Compile config into a middleware, an invalid config is returned as error
+ */
+func New(config Config) (*Middleware, error) {
+	config, err := prepare(config)
+	if err != nil {
+		return nil, err
+	}
+	return newMiddleware(config, compileMatcher(config)), nil
+}
+
+/**
+ * Merge, validate and copy config, reporting warnings
+ */
+func prepare(config Config) (Config, error) {
+	merge(_config, &config)
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	for _, w := range config.warnings() {
+		warn(config, w)
+	}
+	config = clone(config)
+	if config.AutoAllowAuthorization && config.Credentials {
+		config.Headers = withAuthorization(config.Headers)
+		for i := range config.OriginPolicies {
+			if config.OriginPolicies[i].Headers != nil {
+				config.OriginPolicies[i].Headers = withAuthorization(config.OriginPolicies[i].Headers)
+			}
+		}
+		for i := range config.Rules {
+			if config.Rules[i].Headers != nil {
+				config.Rules[i].Headers = withAuthorization(config.Rules[i].Headers)
+			}
+		}
+	}
+	if config.ExposeSafelistedHeaders {
+		config.ExposeHeaders = withoutSafelisted(config.ExposeHeaders)
+		config.ExposeHeadersList = withoutSafelisted(config.ExposeHeadersList)
+	}
+	return config, nil
+}
+
+func newMiddleware(config Config, matcher *OriginMatcher) *Middleware {
+	m := &Middleware{
+		config:  config,
+		origins: matcher.origins,
+		denied:  matcher.denied,
+		routes:  &routeMatchers{items: make(map[routeKey]*originMatcher)},
+	}
+	if matcher.origins.fn == nil && config.OriginResolver == nil {
+		m.cache = newOriginCache(config.OriginCacheSize)
+	}
+	m.resolver = newCachedResolver(config)
+	for _, pol := range config.OriginPolicies {
+		m.policies = append(m.policies, originPolicy{newOriginMatcher(Config{Origin: pol.Origin}), pol})
+	}
+	if len(config.OriginsByHost) > 0 {
+		m.hosts = make(map[string]*originMatcher, len(config.OriginsByHost))
+		for host, list := range config.OriginsByHost {
+			m.hosts[strings.ToLower(host)] = newOriginMatcher(Config{Origin: list})
+		}
+	}
+	if len(config.PrivateNetworkOrigins) > 0 {
+		m.privateNetwork = newOriginMatcher(Config{Origin: config.PrivateNetworkOrigins})
+	}
+	for _, rl := range config.Rules {
+		var matcher *originMatcher
+		if rl.Origin != nil {
+			matcher = newOriginMatcher(Config{Origin: rl.Origin})
+		}
+		m.rules = append(m.rules, rule{rl.PathPrefix, originPolicy{matcher, rl.OriginPolicy}})
+	}
+	return m
+}
+
+/**
+ * First policy matching the origin, nil if none. Policies are never merged, an earlier
+ * broad entry shadows a later specific one.
+ */
+func (m *Middleware) policyFor(origin string) *OriginPolicy {
+	for i := range m.policies {
+		if m.policies[i].matcher.match(origin) {
+			return &m.policies[i].OriginPolicy
+		}
+	}
+	return nil
+}
+
+/**
+ * Effective methods allowed for an origin, considering per-origin policies
+ */
+func (m *Middleware) MethodsFor(origin string) []string {
+	return copySlice(m.methodsFor(origin))
+}
+
+func (m *Middleware) methodsFor(origin string) []string {
+	return m.effective(nil, origin).Methods
+}
+
+/**
+ * First rule whose prefix the path has, nil if none
+ */
+func (m *Middleware) ruleFor(path string) *rule {
+	for i := range m.rules {
+		if strings.HasPrefix(path, m.rules[i].prefix) {
+			return &m.rules[i]
+		}
+	}
+	return nil
+}
+
+/**
+ * Matcher of `OriginsByHost` for the request host, with or without port, nil if none
+ */
+func (m *Middleware) hostMatcher(host string) *originMatcher {
+	if m.hosts == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	if hm, ok := m.hosts[host]; ok {
+		return hm
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return m.hosts[h]
+	}
+	return nil
+}
+
+/**
+ * Effective settings for an origin on the path of a rule (nil for none), unset fields
+ * of the origin policy inherit from the rule, and those of the rule from the global config
+ */
+func (m *Middleware) effective(rl *rule, origin string) OriginPolicy {
+	eff := OriginPolicy{Methods: m.config.Methods, Headers: m.config.Headers, MaxAge: m.config.MaxAge}
+	if rl != nil {
+		if rl.Methods != nil {
+			eff.Methods = rl.Methods
+		}
+		if rl.Headers != nil {
+			eff.Headers = rl.Headers
+		}
+		if rl.MaxAge != 0 {
+			eff.MaxAge = rl.MaxAge
+		}
+	}
+	if pol := m.policyFor(origin); pol != nil {
+		eff.Origin = pol.Origin
+		if pol.Methods != nil {
+			eff.Methods = pol.Methods
+		}
+		if pol.Headers != nil {
+			eff.Headers = pol.Headers
+		}
+		if pol.MaxAge != 0 {
+			eff.MaxAge = pol.MaxAge
+		}
+	}
+	return eff
+}
+
+/**
+ * Rule or origin policy sets its own methods
+ */
+func (m *Middleware) narrowsMethods(rl *rule, origin string) bool {
+	if rl != nil && rl.Methods != nil {
+		return true
+	}
+	pol := m.policyFor(origin)
+	return pol != nil && pol.Methods != nil
+}
+
+/**
+ * Handler to mount on the router
+ */
+func (m *Middleware) Handler() rest.Handler {
+	return m.serve
+}
+
 /**
  * A CORS-preflight request is a CORS request that checks to see if the CORS protocol is understood. It uses `OPTIONS` as method and includes these headers:
  *
@@ -130,73 +938,523 @@ func hasInclude(data []string, val []string) bool {
  *
  * `Access-Control-Request-Headers`
  * Indicates which headers a future CORS request to the same resource might use.
+ *
+ * The result already carries the origin headers, including `Access-Control-Allow-Credentials`
+ * which browsers require on the preflight of a credentialed request as well. Rejections keep
+ * them, so browsers report the failing method or headers rather than a missing allow-origin.
  */
-func corsPreFlightRequest(ctx *rest.Context, config Config) {
-	method := ctx.Request.Header.Get("Access-Control-Request-Method")
-	headers := ctx.Request.Header.Get("Access-Control-Request-Headers")
+func (m *Middleware) preflight(ctx *rest.Context, origin string, res Result) (Result, error) {
+	config := m.config
+	r := ctx.Request
+	rl := m.ruleFor(r.URL.Path)
+	eff := m.effective(rl, origin)
+	methods := eff.Methods
+	res.vary(headerRequestMethod, headerRequestHeaders)
+	method := r.Header.Get(headerRequestMethod)
+	rawHeaders := r.Header.Get(headerRequestHeaders)
+	// counted before the list is split
+	if config.MaxRequestHeaders > 0 && strings.Count(rawHeaders, ",") >= config.MaxRequestHeaders &&
+		m.deny(&res, HeadersNotAllowed) {
+		return res.reject(HeadersNotAllowed)
+	}
+	headers := parseList(rawHeaders)
 
-	if method != "" && !hasMatch(config.Methods, method) {
-		ctx.Status(403).Throw(MethodNotAllowed)
-		return
+	// malformed requests are told apart from policy rejections
+	_, hasMethod := r.Header[headerRequestMethod]
+	malformed := (hasMethod && !isToken(method)) || (strings.TrimSpace(rawHeaders) != "" && len(headers) == 0)
+	for _, h := range headers {
+		if !isToken(h) {
+			malformed = true
+		}
 	}
+	if malformed {
+		if m.deny(&res, MalformedPreflight) {
+			return res.fail(400, MalformedPreflight)
+		}
+		// nothing malformed is echoed, the handlers answer the request
+		return res, nil
+	}
+	allowedAllHeaders := hasMatch(eff.Headers, "*")
 
-	if headers != "" && !hasInclude(config.Headers, strings.Split(headers, ", ")) {
-		ctx.Status(403).Throw(HeadersNotAllowed)
-		return
+	// a report-only violation is answered as if allowed, so the browser goes on with the request
+	var reportMethod, reportHeaders bool
+	if method != "" && (!hasMatch(methods, method) ||
+		(config.SpecCompliant && hasMatch(forbiddenMethods, strings.ToUpper(method)))) {
+		if m.deny(&res, MethodNotAllowed) {
+			return res.reject(MethodNotAllowed)
+		}
+		reportMethod = true
 	}
 
-	if len(config.Methods) > 0 {
-		ctx.SetHeader("Access-Control-Allow-Methods", strings.Join(config.Methods, ", "))
+	// header names are case-insensitive, browsers request them in lower case;
+	// a lone `*` is only accepted through the wildcard, never as a literal header name
+	if len(headers) > 0 && !allowedAllHeaders && !config.AllowRequestedHeaders && !config.LenientHeaders &&
+		!hasIncludeFold(eff.Headers, headers) {
+		if m.deny(&res, HeadersNotAllowed) {
+			return res.reject(HeadersNotAllowed)
+		}
+		reportHeaders = true
 	}
 
-	if len(config.Headers) > 0 {
-		ctx.SetHeader("Access-Control-Allow-Headers", strings.Join(config.Headers, ", "))
+	// the request method is checked against the enforced methods above, whatever is advertised;
+	// only the global methods are replaced, a narrower list is advertised as enforced
+	if config.AdvertisedMethods != nil && !m.narrowsMethods(rl, origin) {
+		methods = config.AdvertisedMethods
+	}
+	// the methods themselves, never shortened to `*`
+	if config.SetAllowHeader && len(methods) > 0 {
+		setList(res.Headers, config, headerAllow, methods)
+	}
+	// `*` is taken literally with credentials, the method that passed the check is echoed instead
+	if config.OmitDefaultMethodsHeader && sameSet(methods, _config.Methods) {
+		if config.Credentials && method != "" {
+			methods = []string{method}
+		} else if !config.Credentials {
+			methods = []string{"*"}
+		}
+	}
+	if reportMethod && !hasMatch(methods, method) {
+		methods = append(copySlice(methods), method)
+	}
+	if len(methods) > 0 {
+		setList(res.Headers, config, headerAllowMethods, methods)
 	}
 
-	if config.MaxAge > time.Duration(0) {
-		ctx.SetHeader("Access-Control-Max-Age", strconv.FormatInt(int64(config.MaxAge/time.Second), 10))
+	// `*` is taken literally for credentialed requests, so reflect requested headers instead;
+	// a requested `*` is never echoed next to credentials
+	if config.AllowRequestedHeaders || reportHeaders || (allowedAllHeaders && config.Credentials) {
+		if config.Credentials {
+			headers = withoutWildcard(headers)
+		}
+		if len(headers) > 0 {
+			if config.CanonicalizeReflectedHeaders {
+				headers = canonical(headers)
+			}
+			setList(res.Headers, config, headerAllowHeaders, headers)
+		}
+	} else if allowedAllHeaders {
+		res.Headers.Set(headerAllowHeaders, "*")
+	} else if config.LenientHeaders && len(headers) > 0 {
+		// the browser then fails the actual request, if it relies on a header left out
+		if common := intersect(headers, eff.Headers); len(common) > 0 {
+			if config.CanonicalizeReflectedHeaders {
+				common = canonical(common)
+			}
+			setList(res.Headers, config, headerAllowHeaders, common)
+		}
+	} else if len(eff.Headers) > 0 {
+		setList(res.Headers, config, headerAllowHeaders, eff.Headers)
+	}
+
+	// private network access is granted to a trusted subset of the allowed origins only
+	if m.privateNetwork != nil {
+		res.vary(headerRequestPrivateNetwork)
+		if r.Header.Get(headerRequestPrivateNetwork) == "true" && m.privateNetwork.match(origin) {
+			res.Headers.Set(headerAllowPrivateNetwork, "true")
+		}
+	}
+
+	maxAge := eff.MaxAge
+	if config.MaxAgeFunc != nil {
+		maxAge = config.MaxAgeFunc(ctx)
+	}
+
+	if maxAge > time.Duration(0) {
+		res.Headers.Set(headerMaxAge, strconv.FormatInt(int64(maxAge/time.Second), 10))
+	}
+
+	// for intermediaries, browsers only honor `Access-Control-Max-Age`
+	if config.PreflightCacheControl != "" {
+		res.Headers.Set(headerCacheControl, config.PreflightCacheControl)
 	}
 
-	ctx.Status(204).Text("")
-	ctx.End()
+	// browsers cache preflight per URL, method and headers; expose the effective seconds
+	if config.DebugHeaders {
+		res.Headers.Set(headerDebugMaxAge, strconv.FormatInt(int64(maxAge/time.Second), 10))
+	}
+
+	// the headers are set, the app handlers answer the request themselves
+	if config.PassPreflightWhen != nil && config.PassPreflightWhen(ctx) {
+		return res, nil
+	}
 
+	res.Status = 204
+	res.End = true
+	return res, nil
 }
 
 /**
- * Cors request
+ * Context key of route scoped allowed origins set by the router, a `*OriginMatcher`
+ * or a `[]string` which must not be modified once set
  */
-func Load(config Config) rest.Handler {
-	merge(_config, &config)
-	allowedAllOrigins := hasMatch(_config.Origin, "*")
-	return func(ctx *rest.Context) {
-		origin := ctx.Request.Header.Get("Origin")
-		// STEP 1: check origin
-		if origin == "" {
-			return
+const ContextOrigins = "cors.origins"
+
+/**
+ * Route scoped origin and deny matchers from context, a list is compiled once per slice
+ */
+func (m *Middleware) routeOrigins(ctx *rest.Context) (*originMatcher, *originMatcher, bool) {
+	val, exists := ctx.Get(ContextOrigins)
+	if !exists {
+		return nil, nil, false
+	}
+	switch v := val.(type) {
+	case *OriginMatcher:
+		if v != nil {
+			return v.origins, v.denied, true
 		}
+	case []string:
+		return m.routes.get(v), nil, true
+	}
+	return nil, nil, false
+}
+
+func isHTTPS(origin string) bool {
+	scheme, _, _, ok := parseOrigin(origin)
+	return ok && scheme == "https"
+}
 
-		// STEP 2: validate origin
-		if !allowedAllOrigins && !hasMatch(config.Origin, origin) {
-			ctx.Status(403)
-			ctx.Throw(OriginNotAllowed)
-			return
+/**
+ * Headers to write for an allowed origin
+ *
+ * The concrete request origin is reflected for every matcher, never the rule that matched it;
+ * `decide` replaces it with `*` when all origins are allowed without credentials.
+ */
+func originHeaders(origin string, config Config) []header {
+	var headers []header
+
+	allowed := origin
+	if config.OriginRewrite != nil {
+		allowed = config.OriginRewrite(origin)
+	}
+	if allowed != "" {
+		headers = append(headers, header{headerAllowOrigin, allowed})
+	}
+
+	//check: https://fetch.spec.whatwg.org/#cors-protocol-and-credentials
+	if config.Credentials && (!config.CredentialsRequireHTTPS || isHTTPS(origin)) {
+		headers = append(headers, header{headerAllowCredentials, "true"})
+	}
+	return headers
+}
+
+/**
+ * Behavior for an `OPTIONS` request without `Access-Control-Request-Method`, -1 for other requests
+ */
+func (m *Middleware) bareOptions(r *http.Request) BareOptions {
+	if r.Method != "OPTIONS" || m.mode == modePreflight {
+		return -1
+	}
+	if _, ok := r.Header[headerRequestMethod]; ok {
+		return -1
+	}
+	if m.config.SpecCompliant {
+		return BareOptionsSimple
+	}
+	return m.config.BareOptionsBehavior
+}
+
+/**
+ * Decide on CORS request and log the decision
+ */
+func (m *Middleware) evaluate(ctx *rest.Context) (Result, error) {
+	res, err := m.decide(ctx)
+	// a report-only policy answered as if allowed, the violation is logged and reported instead
+	if res.violation != nil {
+		logDecision(m.config, ctx.Request, res, res.violation)
+		report(m.config, ctx.Request, res.violation)
+		return res, nil
+	}
+	logDecision(m.config, ctx.Request, res, err)
+	// the browser blocks the response itself, as it lacks `Access-Control-Allow-Origin`
+	if err == OriginNotAllowed && m.config.SoftReject {
+		res.Status, res.End, err = 0, false, nil
+	}
+	// only a rejection which stands is explained
+	if err != nil && m.config.RejectReasonHeader {
+		res.Headers.Set(headerRejectReason, rejectReason(err))
+	}
+	return res, err
+}
+
+/**
+ * Machine readable name of the failed check
+ */
+func rejectReason(err error) string {
+	switch err {
+	case OriginNotAllowed:
+		return "origin"
+	case OriginRequired:
+		return "origin-required"
+	case MethodNotAllowed:
+		return "method"
+	case HeadersNotAllowed:
+		return "headers"
+	case MalformedPreflight:
+		return "malformed"
+	case InsecureRequest:
+		return "insecure"
+	}
+	return "unknown"
+}
+
+/**
+ * Decide on CORS request, rejections are returned as error along with a 403 result
+ */
+func (m *Middleware) decide(ctx *rest.Context) (Result, error) {
+	config := m.config
+	r := ctx.Request
+	res := Result{Headers: make(http.Header)}
+
+	if len(config.PathPrefixes) > 0 && !hasPrefix(config.PathPrefixes, r.URL.Path) {
+		return res, nil
+	}
+
+	if m.mode == modeSimple && r.Method == "OPTIONS" {
+		return res, nil
+	}
+
+	bare := m.bareOptions(r)
+	if bare == BareOptionsPassThrough {
+		return res, nil
+	}
+
+	if config.ResourcePolicy != "" {
+		res.Headers.Set(headerResourcePolicy, config.ResourcePolicy)
+	}
+	if config.OpenerPolicy != "" {
+		res.Headers.Set(headerOpenerPolicy, config.OpenerPolicy)
+	}
+	if config.EmbedderPolicy != "" {
+		res.Headers.Set(headerEmbedderPolicy, config.EmbedderPolicy)
+	}
+
+	origin := r.Header.Get(headerOrigin)
+	// STEP 1: check origin
+	if origin == "" {
+		if config.RequireOrigin && m.deny(&res, OriginRequired) {
+			return res.reject(OriginRequired)
+		}
+		if config.AlwaysSetAllowOrigin && m.origins.all && !(config.SpecCompliant && config.Credentials) {
+			res.Headers.Set(headerAllowOrigin, "*")
 		}
+		return res, nil
+	}
 
-		ctx.SetHeader("Access-Control-Allow-Origin", origin)
+	if config.RequireTLS && r.TLS == nil && m.deny(&res, InsecureRequest) {
+		return res.reject(InsecureRequest)
+	}
 
-		//check: https://fetch.spec.whatwg.org/#cors-protocol-and-credentials
-		if config.Credentials {
-			ctx.SetHeader("Access-Control-Allow-Credentials", "true")
+	// STEP 2: validate origin, oversized values are rejected before any matching work
+	// and control characters could split the response once reflected
+	if config.MaxOriginLength > 0 && len(origin) > config.MaxOriginLength && m.deny(&res, OriginNotAllowed) {
+		return res.reject(OriginNotAllowed)
+	}
+	if hasControlChar(origin) {
+		if m.deny(&res, OriginNotAllowed) {
+			return res.reject(OriginNotAllowed)
+		}
+		// never reflected, even by a report-only policy
+		return res, nil
+	}
+
+	// deny list is checked first and
+	// route scoped origins take precedence over config
+	if m.denied != nil && m.denied.match(origin) && m.deny(&res, OriginNotAllowed) {
+		return res.reject(OriginNotAllowed)
+	}
+
+	if config.CheckSecFetchSite && !secFetchConsistent(r, origin, config) && m.deny(&res, OriginNotAllowed) {
+		return res.reject(OriginNotAllowed)
+	}
+
+	matcher, c, resolver := m.origins, m.cache, m.resolver
+	if hm := m.hostMatcher(r.Host); hm != nil {
+		matcher, c, resolver = hm, nil, nil
+	}
+	if rl := m.ruleFor(r.URL.Path); rl != nil && rl.matcher != nil {
+		matcher, c, resolver = rl.matcher, nil, nil
+	}
+	if rm, denied, ok := m.routeOrigins(ctx); ok {
+		if denied != nil && denied.match(origin) && m.deny(&res, OriginNotAllowed) {
+			return res.reject(OriginNotAllowed)
 		}
+		matcher, c, resolver = rm, nil, nil
+	}
 
-		// STEP 3: check request method
-		if ctx.Request.Method != "OPTIONS" {
-			if len(config.ExposeHeaders) > 0 {
-				ctx.SetHeader("Access-Control-Allow-Headers", strings.Join(config.ExposeHeaders, ", "))
+	// under panic only `ContextOrigins` gets here with `*`, the other lists failed validation
+	if matcher.all && config.Credentials && config.UnsafeWildcardCredentials != WildcardCredentialsReflect &&
+		m.deny(&res, OriginNotAllowed) {
+		return res.reject(OriginNotAllowed)
+	}
+
+	// origins allowed as self, by resolver, client certificate or token skip the header cache: self
+	// depends on the request host, the resolver has its own TTL and the next request may carry no token
+	headers, source, ok := c.get(origin)
+	if !ok {
+		switch source = matcher.source(origin); {
+		case source != MatchNone:
+			headers = originHeaders(origin, config)
+			c.add(origin, headers, source)
+		case matcher.self && isSelf(r, origin, config):
+			source, headers = MatchSelf, originHeaders(origin, config)
+		case resolver.allowed(origin):
+			source, headers = MatchResolver, originHeaders(origin, config)
+		case config.AllowByClientCertSAN && clientCertAllows(r, origin):
+			source, headers = MatchClientCert, originHeaders(origin, config)
+		case config.TokenValidator != nil && config.TokenValidator(ctx, origin):
+			source, headers = MatchToken, originHeaders(origin, config)
+		default:
+			if m.deny(&res, OriginNotAllowed) {
+				return res.reject(OriginNotAllowed)
 			}
-			return
+			headers = originHeaders(origin, config)
 		}
+	}
+	res.Source = source
+
+	for _, h := range headers {
+		res.Headers.Set(h[0], h[1])
+	}
+	// cached headers carry credentials whatever the path, they are dropped per request
+	if len(config.CredentialPaths) > 0 && !matchPath(config.CredentialPaths, r.URL.Path) {
+		res.Headers.Del(headerAllowCredentials)
+	}
+	if source.pattern() && !config.AllowCredentialsForPatterns {
+		res.Headers.Del(headerAllowCredentials)
+	}
+	// only the global `Origin` grants credentials to every origin
+	scoped := source == MatchAll && matcher != m.origins
+	if scoped {
+		res.Headers.Del(headerAllowCredentials)
+	}
+
+	// all origins without credentials get the same literal `*`, only a reflected origin
+	// (or an origin policy) makes the response depend on `Origin`
+	static := source == MatchAll && (!config.Credentials || scoped) && config.OriginRewrite == nil
+	if static {
+		res.Headers.Set(headerAllowOrigin, "*")
+	}
+	if !static || len(m.policies) > 0 {
+		res.vary(headerOrigin)
+	}
+
+	// non-standard, only for machine clients; browsers send `Sec-Fetch-Mode`
+	if config.ListAllowOrigin && r.Header.Get(headerSecFetchMode) == "" && len(matcher.list) > 0 {
+		res.Headers.Set(headerAllowOrigin, strings.Join(matcher.list, ", "))
+	}
+
+	// STEP 3: check request method, a preflight is answered right away
+	isPreflight := r.Method == "OPTIONS"
+	if config.SpecCompliant && r.Header.Get(headerRequestMethod) == "" {
+		isPreflight = false
+	}
+
+	switch {
+	case m.mode == modePreflight:
+		return m.preflight(ctx, origin, res)
+	case bare == BareOptionsRespond:
+		res.Status = 204
+		res.End = true
+		return res, nil
+	case isPreflight && bare != BareOptionsSimple:
+		return m.preflight(ctx, origin, res)
+	}
+
+	if config.EnforceMethodOnSimpleRequest && !hasMatch(config.SimpleMethods, r.Method) &&
+		!hasMatch(m.effective(m.ruleFor(r.URL.Path), origin).Methods, r.Method) && m.deny(&res, MethodNotAllowed) {
+		return res.reject(MethodNotAllowed)
+	}
+
+	// `*` is taken literally for credentialed requests, fall back to the enumerated list
+	expose := config.ExposeHeaders
+	if config.ExposeHeadersFunc != nil {
+		expose = config.ExposeHeadersFunc(ctx)
+		if config.ExposeSafelistedHeaders {
+			expose = withoutSafelisted(expose)
+		}
+	}
+	if res.Headers.Get(headerAllowCredentials) != "" && hasMatch(expose, "*") {
+		expose = config.ExposeHeadersList
+		res.negotiate = len(expose) == 0 && config.NegotiateExposeHeaders
+	}
+	if len(expose) > 0 {
+		res.Headers.Set(headerExposeHeaders, strings.Join(expose, ", "))
+	}
+	return res, nil
+}
+
+/**
+ * Evaluate request without touching any response, the decision is left to the caller.
+ * Functions of the config receive a context holding only the request.
+ */
+func (m *Middleware) Evaluate(r *http.Request) (Result, error) {
+	return m.evaluate(&rest.Context{Request: r})
+}
+
+/**
+ * Evaluate request with a config, for one-offs like tests only: the config is compiled
+ * and its warnings are logged on every call, use `New` and `(*Middleware).Evaluate` per request.
+ * An invalid config is returned as error with an empty result.
+ */
+func Evaluate(config Config, r *http.Request) (Result, error) {
+	m, err := New(config)
+	if err != nil {
+		return Result{}, err
+	}
+	return m.Evaluate(r)
+}
+
+/**
+ * Cors request
+ *
+ * The config is copied and treated as immutable once loaded, so the returned handler
+ * can be shared across goroutines.
+ */
+func Load(config Config) rest.Handler {
+	return mustNew(config, modeAll).Handler()
+}
+
+/**
+ * Preflight only handler, for `OPTIONS` routes
+ *
+ * Every request is treated as a preflight: method and headers are validated, the allow
+ * headers are set and the response ends with the success status.
+ */
+func PreflightHandler(config Config) rest.Handler {
+	return mustNew(config, modePreflight).Handler()
+}
+
+/**
+ * Simple request only handler, sets allow-origin, credentials and expose headers
+ * and never handles preflight, `OPTIONS` requests pass through untouched
+ */
+func SimpleHandler(config Config) rest.Handler {
+	return mustNew(config, modeSimple).Handler()
+}
+
+/**
+ * Cors request with a precompiled origin matcher, see `CompileMatcher`
+ *
+ * `Origin`, `OriginGlobs`, `OriginPatterns`, `OriginRegexes`, `AllowOriginFunc` and `DenyOrigin`
+ * of config are ignored, the matcher decides instead and config is validated against it.
+ */
+func LoadWithMatcher(config Config, matcher *OriginMatcher) rest.Handler {
+	if matcher == nil || matcher.origins == nil {
+		panic("cors: LoadWithMatcher needs a matcher from CompileMatcher")
+	}
+	config.use(matcher.source)
+	config, err := prepare(config)
+	if err != nil {
+		panic("cors: " + err.Error())
+	}
+	return newMiddleware(config, matcher).Handler()
+}
 
-		corsPreFlightRequest(ctx, config)
+func mustNew(config Config, mode mode) *Middleware {
+	m, err := New(config)
+	if err != nil {
+		panic("cors: " + err.Error())
 	}
+	m.mode = mode
+	return m
 }
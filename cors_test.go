@@ -0,0 +1,381 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestMain(m *testing.M) {
+	// config warnings are asserted through `warnings`, not read from the log
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+/**
+ * Responder keeping everything the middleware wrote, calls are recorded in order
+ */
+type recorder struct {
+	req    *http.Request
+	header http.Header
+	status int
+	err    error
+	ended  bool
+	calls  []string
+}
+
+func newRecorder(r *http.Request) *recorder {
+	return &recorder{req: r, header: make(http.Header)}
+}
+
+func (w *recorder) Request() *http.Request {
+	return w.req
+}
+
+func (w *recorder) SetHeader(key string, val string) {
+	w.header.Set(key, val)
+	w.calls = append(w.calls, "header")
+}
+
+func (w *recorder) AddHeader(key string, val string) {
+	w.header.Add(key, val)
+	w.calls = append(w.calls, "header")
+}
+
+func (w *recorder) Status(code int) {
+	w.status = code
+	w.calls = append(w.calls, "status")
+}
+
+func (w *recorder) Throw(err error) {
+	w.err = err
+	w.calls = append(w.calls, "throw")
+}
+
+func (w *recorder) Text(data string) {
+	w.calls = append(w.calls, "text")
+}
+
+func (w *recorder) End() {
+	w.ended = true
+	w.calls = append(w.calls, "end")
+}
+
+/**
+ * Request with header name and value pairs
+ */
+func request(method string, target string, headers ...string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	return r
+}
+
+/**
+ * Preflight of origin for method, and the comma joined request headers unless empty
+ */
+func preflightRequest(target string, origin string, method string, headers string) *http.Request {
+	r := request("OPTIONS", target, headerOrigin, origin, headerRequestMethod, method)
+	if headers != "" {
+		r.Header.Set(headerRequestHeaders, headers)
+	}
+	return r
+}
+
+func mustLoad(t testing.TB, config Config) *Middleware {
+	t.Helper()
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func do(m *Middleware, r *http.Request) *recorder {
+	w := newRecorder(r)
+	m.Respond(w)
+	return w
+}
+
+func serve(t testing.TB, config Config, r *http.Request) *recorder {
+	t.Helper()
+	return do(mustLoad(t, config), r)
+}
+
+func expectHeader(t testing.TB, h http.Header, name string, want string) {
+	t.Helper()
+	if got := strings.Join(h.Values(name), "|"); got != want {
+		t.Errorf("%s = %q, want %q", name, got, want)
+	}
+}
+
+func TestSimpleRequest(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.example.com"}, ExposeHeaders: []string{"X-Total-Count"}})
+
+	w := do(m, request("GET", "/", headerOrigin, "https://app.example.com"))
+	if w.err != nil || w.ended {
+		t.Fatalf("err = %v, ended = %v", w.err, w.ended)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.example.com")
+	expectHeader(t, w.header, headerVary, headerOrigin)
+	expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count")
+	expectHeader(t, w.header, headerAllowCredentials, "")
+
+	w = do(m, request("GET", "/"))
+	if w.err != nil || len(w.header) != 0 {
+		t.Errorf("request without origin: err = %v, headers = %v", w.err, w.header)
+	}
+}
+
+func TestRejection(t *testing.T) {
+	w := serve(t, Config{Origin: []string{"https://app.example.com"}}, request("GET", "/", headerOrigin, "https://evil.example.com"))
+	if w.err != OriginNotAllowed || w.status != 403 {
+		t.Errorf("err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "")
+}
+
+func TestPreflight(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.example.com"}})
+
+	w := do(m, preflightRequest("/", "https://app.example.com", "PUT", "Content-Type"))
+	if w.err != nil || w.status != 204 || !w.ended {
+		t.Fatalf("err = %v, status = %d, ended = %v", w.err, w.status, w.ended)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type")
+	expectHeader(t, w.header, headerMaxAge, "3600")
+	expectHeader(t, w.header, headerVary, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	if w = do(m, preflightRequest("/", "https://app.example.com", "PURGE", "")); w.err != MethodNotAllowed {
+		t.Errorf("method: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/", "https://app.example.com", "PUT", "X-Secret")); w.err != HeadersNotAllowed {
+		t.Errorf("headers: err = %v", w.err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}})
+
+	res, err := m.Evaluate(preflightRequest("/", "https://app.com", "PUT", ""))
+	if err != nil || res.Status != 204 || !res.End || res.Source != MatchExact {
+		t.Errorf("preflight: %+v, %v", res, err)
+	}
+	if want := []string{headerOrigin, headerRequestMethod, headerRequestHeaders}; strings.Join(res.Vary, ",") != strings.Join(want, ",") {
+		t.Errorf("vary = %v, want %v", res.Vary, want)
+	}
+
+	res, err = m.Evaluate(request("GET", "/", headerOrigin, "https://evil.com"))
+	if err != OriginNotAllowed || res.Status != 403 || !res.End || res.Source != MatchNone {
+		t.Errorf("rejection: %+v, %v", res, err)
+	}
+
+	if _, err = Evaluate(Config{ResourcePolicy: "nobody"}, request("GET", "/")); err != InvalidResourcePolicy {
+		t.Errorf("invalid config: err = %v", err)
+	}
+}
+
+func TestAlwaysSetAllowOrigin(t *testing.T) {
+	asset := request("GET", "/fonts/a.woff2")
+	w := serve(t, Config{AlwaysSetAllowOrigin: true}, asset)
+	expectHeader(t, w.header, headerAllowOrigin, "*")
+	expectHeader(t, w.header, headerVary, "")
+
+	// without all origins allowed there is no value valid for every client
+	w = serve(t, Config{Origin: []string{"https://app.com"}, AlwaysSetAllowOrigin: true}, asset)
+	expectHeader(t, w.header, headerAllowOrigin, "")
+	// nor with credentials in spec compliant mode
+	w = serve(t, Config{AlwaysSetAllowOrigin: true, Credentials: true, SpecCompliant: true}, asset)
+	expectHeader(t, w.header, headerAllowOrigin, "")
+
+	w = serve(t, Config{}, asset)
+	expectHeader(t, w.header, headerAllowOrigin, "")
+}
+
+func TestSpecCompliant(t *testing.T) {
+	config := Config{
+		Methods:              []string{"GET", "PUT", "TRACE"},
+		Headers:              []string{"*"},
+		ExposeHeaders:        []string{"*"},
+		Credentials:          true,
+		AlwaysSetAllowOrigin: true,
+		SpecCompliant:        true,
+	}
+	m := mustLoad(t, config)
+
+	// forbidden methods are rejected, even when listed
+	for _, method := range []string{"TRACE", "trace"} {
+		if w := do(m, preflightRequest("/", "https://app.com", method, "")); w.err != MethodNotAllowed || w.status != 403 {
+			t.Errorf("%s: err = %v, status = %d", method, w.err, w.status)
+		}
+	}
+
+	// every wildcard is answered without `*` next to credentials
+	w := do(m, preflightRequest("/", "https://app.com", "PUT", "*, X-Custom"))
+	if w.err != nil || w.status != 204 {
+		t.Fatalf("preflight: err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+	expectHeader(t, w.header, headerAllowMethods, "GET, PUT, TRACE")
+	expectHeader(t, w.header, headerAllowHeaders, "X-Custom")
+	expectHeader(t, w.header, headerVary, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	w = do(m, request("GET", "/", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerExposeHeaders, "")
+	expectHeader(t, w.header, headerVary, headerOrigin)
+	w = do(m, request("GET", "/"))
+	expectHeader(t, w.header, headerAllowOrigin, "")
+
+	// only the mode rejects them
+	config.SpecCompliant = false
+	if w = serve(t, config, preflightRequest("/", "https://app.com", "TRACE", "")); w.err != nil || w.status != 204 {
+		t.Errorf("not compliant: err = %v, status = %d", w.err, w.status)
+	}
+}
+
+func TestNilContext(t *testing.T) {
+	m := mustLoad(t, Config{})
+	for _, ctx := range []*rest.Context{nil, {}} {
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); !strings.HasPrefix(msg, "cors:") {
+					t.Errorf("panic = %q, want a cors: message", msg)
+				}
+			}()
+			m.Handler()(ctx)
+		}()
+	}
+}
+
+/**
+ * Responder keeping header names exactly as set, like a framework that doesn't canonicalize
+ */
+type rawRecorder struct {
+	*recorder
+	names map[string]bool
+}
+
+func (w rawRecorder) SetHeader(key string, val string) {
+	w.names[key] = true
+	w.recorder.SetHeader(key, val)
+}
+
+func (w rawRecorder) AddHeader(key string, val string) {
+	w.names[key] = true
+	w.recorder.AddHeader(key, val)
+}
+
+func TestCanonicalHeaderNames(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, Credentials: true, ExposeHeaders: []string{"X-Total-Count"}})
+	want := map[string]bool{
+		"Access-Control-Allow-Origin":      true,
+		"Access-Control-Allow-Credentials": true,
+		"Access-Control-Allow-Methods":     true,
+		"Access-Control-Allow-Headers":     true,
+		"Access-Control-Max-Age":           true,
+		"Vary":                             true,
+	}
+	w := rawRecorder{newRecorder(preflightRequest("/", "https://app.com", "PUT", "content-type")), map[string]bool{}}
+	m.Respond(w)
+	if !reflect.DeepEqual(w.names, want) {
+		t.Errorf("preflight header names = %v, want %v", w.names, want)
+	}
+
+	w = rawRecorder{newRecorder(request("GET", "/", headerOrigin, "https://app.com")), map[string]bool{}}
+	m.Respond(w)
+	for name := range w.names {
+		if name != http.CanonicalHeaderKey(name) {
+			t.Errorf("header name %q is not canonical", name)
+		}
+	}
+	if !w.names["Access-Control-Expose-Headers"] {
+		t.Errorf("header names = %v", w.names)
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, PathPrefixes: []string{"/api/", "/graphql"}})
+
+	w := do(m, request("GET", "/api/items", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	if w = do(m, preflightRequest("/graphql", "https://app.com", "POST", "")); w.status != 204 || !w.ended {
+		t.Errorf("preflight: status = %d, ended = %v", w.status, w.ended)
+	}
+	if w = do(m, request("GET", "/api/items", headerOrigin, "https://evil.com")); w.err != OriginNotAllowed {
+		t.Errorf("rejection: err = %v", w.err)
+	}
+
+	// other paths pass through untouched, rejections and preflights included
+	for _, r := range []*http.Request{
+		request("GET", "/assets/app.js", headerOrigin, "https://evil.com"),
+		preflightRequest("/api", "https://app.com", "POST", ""),
+	} {
+		if w = do(m, r); len(w.calls) != 0 {
+			t.Errorf("%s %s: calls = %v", r.Method, r.URL.Path, w.calls)
+		}
+	}
+}
+
+func TestResultVary(t *testing.T) {
+	list := mustLoad(t, Config{Origin: []string{"https://app.com"}})
+	all := mustLoad(t, Config{})
+	cases := []struct {
+		name string
+		m    *Middleware
+		r    *http.Request
+		want []string
+	}{
+		{"simple", list, request("GET", "/", headerOrigin, "https://app.com"), []string{headerOrigin}},
+		// only a reflected origin adds `Origin`
+		{"rejected", list, request("GET", "/", headerOrigin, "https://evil.com"), nil},
+		{"preflight", list, preflightRequest("/", "https://app.com", "PUT", ""), []string{headerOrigin, headerRequestMethod, headerRequestHeaders}},
+		// `*` is the same for every origin
+		{"static", all, request("GET", "/", headerOrigin, "https://app.com"), nil},
+		{"static preflight", all, preflightRequest("/", "https://app.com", "PUT", ""), []string{headerRequestMethod, headerRequestHeaders}},
+	}
+	for _, c := range cases {
+		res, _ := c.m.Evaluate(c.r)
+		if !reflect.DeepEqual(res.Vary, c.want) {
+			t.Errorf("%s: vary = %q, want %q", c.name, res.Vary, c.want)
+		}
+		expectHeader(t, res.Headers, headerVary, strings.Join(c.want, ", "))
+	}
+}
+
+func TestVaryOrigin(t *testing.T) {
+	r := request("GET", "/", headerOrigin, "https://app.com")
+	for _, c := range []struct {
+		name   string
+		config Config
+		origin string
+		vary   string
+	}{
+		{"static", Config{}, "*", ""},
+		{"listed", Config{Origin: []string{"https://app.com"}}, "https://app.com", headerOrigin},
+		{"credentials", Config{Credentials: true}, "https://app.com", headerOrigin},
+		// `*` for every origin, but the policy of another origin differs
+		{"policies", Config{OriginPolicies: []OriginPolicy{{Origin: []string{"https://partner.com"}, Methods: []string{"GET"}}}}, "*", headerOrigin},
+	} {
+		w := serve(t, c.config, r)
+		expectHeader(t, w.header, headerAllowOrigin, c.origin)
+		if got := w.header.Get(headerVary); got != c.vary {
+			t.Errorf("%s: vary = %q, want %q", c.name, got, c.vary)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+ * Load config from environment variables
+ *
+ * Reads `<PREFIX>_ORIGINS`, `<PREFIX>_METHODS`, `<PREFIX>_HEADERS` and `<PREFIX>_EXPOSE_HEADERS`
+ * as comma separated lists, `<PREFIX>_CREDENTIALS` as bool and `<PREFIX>_MAX_AGE` as duration.
+ * Absent variables are left unset, so defaults apply at `Load`.
+ */
+func FromEnv(prefix string) (Config, error) {
+	var config Config
+	if prefix != "" {
+		prefix += "_"
+	}
+
+	config.Origin = envList(prefix + "ORIGINS")
+	config.Methods = envList(prefix + "METHODS")
+	config.Headers = envList(prefix + "HEADERS")
+	config.ExposeHeaders = envList(prefix + "EXPOSE_HEADERS")
+
+	if v, ok := os.LookupEnv(prefix + "CREDENTIALS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("%sCREDENTIALS: %w", prefix, err)
+		}
+		config.Credentials = b
+	}
+
+	if v, ok := os.LookupEnv(prefix + "MAX_AGE"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("%sMAX_AGE: %w", prefix, err)
+		}
+		config.MaxAge = d
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+/**
+ * Comma separated list from env, nil when absent
+ */
+func envList(name string) []string {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	list := parseList(v)
+	if list == nil {
+		list = []string{}
+	}
+	return list
+}
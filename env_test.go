@@ -0,0 +1,50 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("API_CORS_ORIGINS", "https://app.com, https://admin.app.com")
+	t.Setenv("API_CORS_METHODS", "GET,POST")
+	t.Setenv("API_CORS_EXPOSE_HEADERS", "")
+	t.Setenv("API_CORS_CREDENTIALS", "true")
+	t.Setenv("API_CORS_MAX_AGE", "10m")
+
+	config, err := FromEnv("API_CORS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Origin:        []string{"https://app.com", "https://admin.app.com"},
+		Methods:       []string{"GET", "POST"},
+		ExposeHeaders: []string{},
+		Credentials:   true,
+		MaxAge:        10 * time.Minute,
+	}
+	// absent HEADERS keeps the default, an empty value is none
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+
+	if config, err = FromEnv("NONE"); err != nil || !reflect.DeepEqual(config, Config{}) {
+		t.Errorf("absent: %+v, %v", config, err)
+	}
+
+	for name, val := range map[string]string{"API_CORS_CREDENTIALS": "yes please", "API_CORS_MAX_AGE": "3600"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, val)
+			if _, err := FromEnv("API_CORS"); err == nil || !strings.HasPrefix(err.Error(), name+":") {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+/**
+ * Response writer deferring `Access-Control-Expose-Headers` until the header block is committed
+ *
+ * With credentials `*` is not honored, so the names of the headers actually present on the
+ * response are exposed instead, leaving out safelisted, CORS and cookie headers. Flushing,
+ * hijacking and server push are forwarded to the wrapped writer.
+ */
+type exposeWriter struct {
+	http.ResponseWriter
+	done bool
+}
+
+func (w *exposeWriter) WriteHeader(code int) {
+	w.expose()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *exposeWriter) Write(b []byte) (int, error) {
+	w.expose()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *exposeWriter) Flush() {
+	w.expose()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *exposeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *exposeWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+/**
+ * Underlying writer, for `http.ResponseController`
+ */
+func (w *exposeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *exposeWriter) expose() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	h := w.Header()
+	var names []string
+	for name := range h {
+		lower := strings.ToLower(name)
+		if hasMatch(safelistedResponseHeaders, lower) || strings.HasPrefix(lower, "access-control-") ||
+			lower == "set-cookie" || lower == "set-cookie2" || lower == "vary" || lower == "trailer" {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	h.Set(headerExposeHeaders, strings.Join(names, ", "))
+}
@@ -0,0 +1,139 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func TestExposeWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &exposeWriter{ResponseWriter: rec}
+	w.Header().Set("X-Total-Count", "3")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Set-Cookie", "a=b")
+	w.WriteHeader(200)
+	w.Header().Set("X-Late", "1")
+	w.Write([]byte("{}"))
+	expectHeader(t, rec.Result().Header, headerExposeHeaders, "X-Total-Count")
+
+	if _, _, err := w.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("hijack: err = %v", err)
+	}
+	if err := w.Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("push: err = %v", err)
+	}
+
+	h := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, _, err := (&exposeWriter{ResponseWriter: h}).Hijack(); err != nil || !h.hijacked {
+		t.Errorf("hijack: err = %v, hijacked = %v", err, h.hijacked)
+	}
+	if w.Unwrap() != rec {
+		t.Error("unwrap does not give the wrapped writer")
+	}
+}
+
+func TestCredentialedExposeWildcard(t *testing.T) {
+	var buf bytes.Buffer
+	config := Config{Origin: []string{"https://app.com"}, Credentials: true, ExposeHeaders: []string{"*"}, Logger: log.New(&buf, "", 0)}
+	r := request("GET", "/", headerOrigin, "https://app.com")
+
+	w := do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "")
+	if want := "cors: expose headers * is ignored by browsers with credentials, set ExposeHeadersList\n"; buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	config.ExposeHeadersList = []string{"X-Total-Count", "X-Page"}
+	w = do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count, X-Page")
+	if buf.Len() != 0 {
+		t.Errorf("logged %q with a list", buf.String())
+	}
+
+	// without credentials `*` is honored
+	config.Credentials = false
+	w = do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "*")
+}
+
+func TestExposeSafelistedHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	config := Config{
+		ExposeHeaders:           []string{"Content-Type", "X-Total-Count", "cache-control"},
+		ExposeSafelistedHeaders: true,
+		Logger:                  log.New(&buf, "", 0),
+	}
+	r := request("GET", "/", headerOrigin, "https://app.com")
+
+	w := do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count")
+	want := "cors: expose header Content-Type is safelisted and exposed anyway\n" +
+		"cors: expose header cache-control is safelisted and exposed anyway\n"
+	if buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+
+	// only safelisted names leave nothing to expose
+	config.ExposeHeaders = []string{"Content-Length"}
+	w = do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "")
+
+	config.ExposeSafelistedHeaders = false
+	w = do(mustLoad(t, config), r)
+	expectHeader(t, w.header, headerExposeHeaders, "Content-Length")
+}
+
+func TestExposeHeadersFunc(t *testing.T) {
+	config := Config{
+		ExposeHeaders: []string{"X-Request-Id"},
+		ExposeHeadersFunc: func(ctx *rest.Context) []string {
+			if strings.HasPrefix(ctx.Request.URL.Path, "/items") {
+				return []string{"X-Total-Count", "Link", "Content-Type"}
+			}
+			return nil
+		},
+	}
+	m := mustLoad(t, config)
+
+	w := do(m, request("GET", "/items?page=2", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count, Link, Content-Type")
+	// replaces the static list
+	w = do(m, request("GET", "/users/1", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerExposeHeaders, "")
+	// never asked on preflight
+	w = do(m, preflightRequest("/items", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerExposeHeaders, "")
+
+	config.ExposeSafelistedHeaders = true
+	w = serve(t, config, request("GET", "/items", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count, Link")
+
+	config.ExposeHeadersFunc = nil
+	w = serve(t, config, request("GET", "/users/1", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerExposeHeaders, "X-Request-Id")
+}
@@ -0,0 +1,57 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"strings"
+)
+
+/**
+ * Origin glob compiled at `Load`, e.g. `https://*.corp.*.example.com`
+ *
+ * A `*` matches one or more characters within a single host label, it never
+ * crosses `.`, `:` or `/`. Stored as the literal parts between the stars.
+ */
+type glob []string
+
+func compileGlob(pattern string) glob {
+	return strings.Split(pattern, "*")
+}
+
+func (g glob) match(origin string) bool {
+	if !strings.HasPrefix(origin, g[0]) {
+		return false
+	}
+	if len(g) == 1 {
+		return origin == g[0]
+	}
+	return matchStar(g[1:], origin[len(g[0]):])
+}
+
+/**
+ * Match a star followed by the literal parts
+ */
+func matchStar(parts []string, s string) bool {
+	for i := 1; i <= len(s); i++ {
+		if strings.IndexByte(".:/", s[i-1]) >= 0 {
+			return false
+		}
+		rest := s[i:]
+		if !strings.HasPrefix(rest, parts[0]) {
+			continue
+		}
+		if len(parts) == 1 {
+			if rest == parts[0] {
+				return true
+			}
+			continue
+		}
+		if matchStar(parts[1:], rest[len(parts[0]):]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+)
+
+func TestOriginGlobs(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{}, OriginGlobs: []string{"https://*.corp.*.example.com", "https://app-*.example.com:443", "http://*.local.example.net."}})
+	for origin, want := range map[string]bool{
+		"https://a.corp.eu.example.com":     true,
+		"https://A.Corp.EU.example.com":     true,
+		"https://a.corp.eu.example.com.":    true,
+		"https://a.corp.eu.example.com:443": true,
+		"https://app-42.example.com":        true,
+		"http://a.local.example.net":        true,
+		"http://a.local.example.net:80":     true,
+		"http://a.local.example.net:8080":   false,
+		"https://a.b.corp.eu.example.com":   false,
+		"https://a.corp.example.com":        false,
+		"https://a.corp.eu.example.org":     false,
+		"http://a.corp.eu.example.com":      false,
+		"https://a.corp.eu.example.com:8":   false,
+		"https://app-.example.com":          false,
+		"https://app-1.2.example.com":       false,
+	} {
+		res, err := m.Evaluate(request("GET", "/", headerOrigin, origin))
+		if got := err == nil; got != want {
+			t.Errorf("%s: allowed = %v, want %v", origin, got, want)
+		}
+		if want && res.Source != MatchGlob {
+			t.Errorf("%s: source = %v", origin, res.Source)
+		}
+	}
+}
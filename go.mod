@@ -0,0 +1,3 @@
+module github.com/go-rs/cors
+
+go 1.21
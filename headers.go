@@ -0,0 +1,34 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+/**
+ * Header names in canonical form, so the response never depends on
+ * whether `SetHeader` canonicalizes names itself
+ */
+const (
+	headerOrigin                = "Origin"
+	headerVary                  = "Vary"
+	headerAllow                 = "Allow"
+	headerRequestMethod         = "Access-Control-Request-Method"
+	headerRequestHeaders        = "Access-Control-Request-Headers"
+	headerAllowOrigin           = "Access-Control-Allow-Origin"
+	headerAllowCredentials      = "Access-Control-Allow-Credentials"
+	headerAllowMethods          = "Access-Control-Allow-Methods"
+	headerAllowHeaders          = "Access-Control-Allow-Headers"
+	headerExposeHeaders         = "Access-Control-Expose-Headers"
+	headerMaxAge                = "Access-Control-Max-Age"
+	headerCacheControl          = "Cache-Control"
+	headerRequestPrivateNetwork = "Access-Control-Request-Private-Network"
+	headerAllowPrivateNetwork   = "Access-Control-Allow-Private-Network"
+	headerResourcePolicy        = "Cross-Origin-Resource-Policy"
+	headerOpenerPolicy          = "Cross-Origin-Opener-Policy"
+	headerEmbedderPolicy        = "Cross-Origin-Embedder-Policy"
+	headerSecFetchSite          = "Sec-Fetch-Site"
+	headerSecFetchMode          = "Sec-Fetch-Mode"
+	headerDebugMaxAge           = "X-Cors-Maxage-Seconds"
+	headerRejectReason          = "X-Cors-Reason"
+)
@@ -0,0 +1,71 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+/**
+ * Responder over a plain `http.ResponseWriter`, the status is written when the response ends
+ */
+type httpResponder struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	status int
+	ended  bool
+}
+
+func (w *httpResponder) Request() *http.Request {
+	return w.r
+}
+
+func (w *httpResponder) SetHeader(key string, val string) {
+	w.w.Header().Set(key, val)
+}
+
+func (w *httpResponder) AddHeader(key string, val string) {
+	w.w.Header().Add(key, val)
+}
+
+func (w *httpResponder) Status(code int) {
+	w.status = code
+}
+
+// no error middleware here, the error code is the plain text body
+func (w *httpResponder) Throw(err error) {
+	http.Error(w.w, err.Error(), w.status)
+	w.ended = true
+}
+
+func (w *httpResponder) Text(data string) {}
+
+func (w *httpResponder) End() {
+	w.w.WriteHeader(w.status)
+	w.ended = true
+}
+
+/**
+ * Middleware for `net/http`, a response ended by CORS never reaches next
+ *
+ * Rejections are answered with their status and the error code as body. Functions of the
+ * config receive a context holding only the request.
+ */
+func (m *Middleware) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hw := &httpResponder{w: w, r: r}
+		res := m.respond(hw, &rest.Context{Request: r})
+		if hw.ended {
+			return
+		}
+		if res.negotiate {
+			w = &exposeWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,75 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPHandlerHTTP2(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:                 []string{"https://app.com"},
+		Credentials:            true,
+		ExposeHeaders:          []string{"*"},
+		NegotiateExposeHeaders: true,
+	})
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("X-Total-Count", "2")
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(" second"))
+		w.Header().Set("X-Checksum", "abc")
+	})
+	srv := httptest.NewUnstartedServer(m.HTTPHandler(app))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	send := func(r *http.Request) *http.Response {
+		resp, err := srv.Client().Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("served over %s", resp.Proto)
+		}
+		return resp
+	}
+
+	r, _ := http.NewRequest("GET", srv.URL, nil)
+	r.Header.Set(headerOrigin, "https://app.com")
+	resp := send(r)
+	// everything CORS sets is in the header block, before the first body byte
+	expectHeader(t, resp.Header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, resp.Header, headerAllowCredentials, "true")
+	expectHeader(t, resp.Header, headerExposeHeaders, "X-Total-Count")
+	expectHeader(t, resp.Trailer, "X-Checksum", "abc")
+	for name := range resp.Trailer {
+		if name != "X-Checksum" {
+			t.Errorf("trailer %s", name)
+		}
+	}
+
+	r, _ = http.NewRequest("OPTIONS", srv.URL, nil)
+	r.Header.Set(headerOrigin, "https://app.com")
+	r.Header.Set(headerRequestMethod, "PUT")
+	if resp = send(r); resp.StatusCode != 204 {
+		t.Errorf("preflight: %d", resp.StatusCode)
+	}
+	expectHeader(t, resp.Header, headerAllowMethods, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
+
+	r, _ = http.NewRequest("GET", srv.URL, nil)
+	r.Header.Set(headerOrigin, "https://evil.com")
+	if resp = send(r); resp.StatusCode != 403 {
+		t.Errorf("rejection: %d", resp.StatusCode)
+	}
+}
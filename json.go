@@ -0,0 +1,175 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+// Config without methods, so it can be embedded without recursing into MarshalJSON
+type configFields Config
+
+/**
+ * JSON form of config, fields below shadow the embedded ones
+ *
+ * Functions, validated regexes, the resolver and the loggers can't be serialized, they are always omitted and ignored on input.
+ */
+type configJSON struct {
+	configFields
+	MaxAge         string   `json:",omitempty"`
+	ResolverTTL    string   `json:",omitempty"`
+	OriginPatterns []string `json:",omitempty"`
+
+	OriginRegexes     json.RawMessage `json:",omitempty"`
+	MaxAgeFunc        json.RawMessage `json:",omitempty"`
+	ExposeHeadersFunc json.RawMessage `json:",omitempty"`
+	AllowOriginFunc   json.RawMessage `json:",omitempty"`
+	TokenValidator    json.RawMessage `json:",omitempty"`
+	OriginRewrite     json.RawMessage `json:",omitempty"`
+	OriginResolver    json.RawMessage `json:",omitempty"`
+	ReportFunc        json.RawMessage `json:",omitempty"`
+	PassPreflightWhen json.RawMessage `json:",omitempty"`
+	Logger            json.RawMessage `json:",omitempty"`
+	StructuredLogger  json.RawMessage `json:",omitempty"`
+}
+
+/**
+ * Marshal config, durations as string ("1h0m0s") and patterns as their source
+ */
+func (c Config) MarshalJSON() ([]byte, error) {
+	out := configJSON{configFields: configFields(c)}
+	if c.MaxAge != 0 {
+		out.MaxAge = c.MaxAge.String()
+	}
+	if c.ResolverTTL != 0 {
+		out.ResolverTTL = c.ResolverTTL.String()
+	}
+	for _, p := range c.OriginPatterns {
+		out.OriginPatterns = append(out.OriginPatterns, p.String())
+	}
+	return json.Marshal(out)
+}
+
+/**
+ * Unmarshal config, durations accept any `time.ParseDuration` string ("1h")
+ */
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var in configJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	config := Config(in.configFields)
+	if in.MaxAge != "" {
+		d, err := time.ParseDuration(in.MaxAge)
+		if err != nil {
+			return err
+		}
+		config.MaxAge = d
+	}
+	if in.ResolverTTL != "" {
+		d, err := time.ParseDuration(in.ResolverTTL)
+		if err != nil {
+			return err
+		}
+		config.ResolverTTL = d
+	}
+	for _, p := range in.OriginPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return err
+		}
+		config.OriginPatterns = append(config.OriginPatterns, re)
+	}
+	*c = config
+	return nil
+}
+
+// Origin policy without methods, see `configFields`
+type policyFields OriginPolicy
+
+/**
+ * JSON form of origin policy, with `MaxAge` as string like in config
+ */
+type policyJSON struct {
+	policyFields
+	MaxAge string `json:",omitempty"`
+}
+
+func newPolicyJSON(p OriginPolicy) policyJSON {
+	out := policyJSON{policyFields: policyFields(p)}
+	if p.MaxAge != 0 {
+		out.MaxAge = p.MaxAge.String()
+	}
+	return out
+}
+
+func (in policyJSON) policy() (OriginPolicy, error) {
+	p := OriginPolicy(in.policyFields)
+	if in.MaxAge != "" {
+		d, err := time.ParseDuration(in.MaxAge)
+		if err != nil {
+			return p, err
+		}
+		p.MaxAge = d
+	}
+	return p, nil
+}
+
+/**
+ * Marshal origin policy, `MaxAge` as string ("10m0s")
+ */
+func (p OriginPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newPolicyJSON(p))
+}
+
+/**
+ * Unmarshal origin policy, `MaxAge` accepts any `time.ParseDuration` string
+ */
+func (p *OriginPolicy) UnmarshalJSON(data []byte) error {
+	var in policyJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	policy, err := in.policy()
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+/**
+ * JSON form of rule, the policy fields sit next to `PathPrefix`
+ */
+type ruleJSON struct {
+	PathPrefix string
+	policyJSON
+}
+
+/**
+ * Marshal rule as one object, otherwise the promoted `OriginPolicy.MarshalJSON` would drop `PathPrefix`
+ */
+func (rl Rule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ruleJSON{PathPrefix: rl.PathPrefix, policyJSON: newPolicyJSON(rl.OriginPolicy)})
+}
+
+/**
+ * Unmarshal rule, see `MarshalJSON`
+ */
+func (rl *Rule) UnmarshalJSON(data []byte) error {
+	var in ruleJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	policy, err := in.policy()
+	if err != nil {
+		return err
+	}
+	*rl = Rule{PathPrefix: in.PathPrefix, OriginPolicy: policy}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	config := Config{
+		Origin:          []string{"https://app.com"},
+		MaxAge:          time.Hour,
+		OriginPatterns:  []*regexp.Regexp{regexp.MustCompile(`^https://pr-[0-9]+\.example\.com$`)},
+		AllowOriginFunc: func(string) bool { return true },
+		OriginPolicies:  []OriginPolicy{{Origin: []string{"https://partner.com"}, MaxAge: 10 * time.Minute}},
+		Rules:           []Rule{{PathPrefix: "/api/public", OriginPolicy: OriginPolicy{Origin: []string{"*"}, MaxAge: time.Minute}}},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"MaxAge":"1h0m0s"`,
+		`"OriginPatterns":["^https://pr-[0-9]+\\.example\\.com$"]`,
+		`"OriginPolicies":[{"Origin":["https://partner.com"],"Methods":null,"Headers":null,"MaxAge":"10m0s"}]`,
+		`"Rules":[{"PathPrefix":"/api/public","Origin":["*"],"Methods":null,"Headers":null,"MaxAge":"1m0s"}]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s lacks %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "AllowOriginFunc") {
+		t.Errorf("%s holds a function", data)
+	}
+
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.MaxAge != time.Hour || out.OriginPatterns[0].String() != config.OriginPatterns[0].String() {
+		t.Errorf("config = %+v", out)
+	}
+	if !reflect.DeepEqual(out.OriginPolicies, config.OriginPolicies) || !reflect.DeepEqual(out.Rules, config.Rules) {
+		t.Errorf("policies = %+v, rules = %+v", out.OriginPolicies, out.Rules)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Rules":[{"PathPrefix":"/a","MaxAge":"2h"}]}`), &out); err != nil || out.Rules[0].MaxAge != 2*time.Hour {
+		t.Errorf("rule: %+v, %v", out.Rules, err)
+	}
+	if err := json.Unmarshal([]byte(`{"OriginPolicies":[{"MaxAge":"soon"}]}`), &out); err == nil {
+		t.Error("malformed duration accepted")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	config := Config{
+		Origin:         []string{"https://app.com", "https://*.example.com"},
+		Methods:        []string{"GET", "POST"},
+		Headers:        []string{"Content-Type"},
+		ExposeHeaders:  []string{"X-Total-Count"},
+		Credentials:    true,
+		MaxAge:         90 * time.Minute,
+		ResolverTTL:    time.Minute,
+		OriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://a\.com$`), regexp.MustCompile(`(?i)^https://b\.com$`)},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.OriginPatterns) != 2 || !out.OriginPatterns[1].MatchString("https://B.com") {
+		t.Errorf("patterns = %v", out.OriginPatterns)
+	}
+	// compiled patterns differ in internal state, their sources are compared above
+	out.OriginPatterns, config.OriginPatterns = nil, nil
+	if !reflect.DeepEqual(out, config) {
+		t.Errorf("round trip = %+v, want %+v", out, config)
+	}
+
+	// as written by hand in a config file
+	if err := json.Unmarshal([]byte(`{"MaxAge":"1h","OriginPatterns":["^https://c\\.com$"]}`), &out); err != nil || out.MaxAge != time.Hour || !out.OriginPatterns[0].MatchString("https://c.com") {
+		t.Errorf("config file: %+v, %v", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"OriginPatterns":["("]}`), &out); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	if err := json.Unmarshal([]byte(`{"MaxAge":3600}`), &out); err == nil {
+		t.Error("number accepted as duration")
+	}
+}
@@ -0,0 +1,60 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+/**
+ * Compiled origin matchers of a config, immutable and safe to share across handlers
+ */
+type OriginMatcher struct {
+	origins *originMatcher
+	// nil without `DenyOrigin` entries
+	denied *originMatcher
+	// compiled fields, validated in place of those of a config loaded with the matcher
+	source Config
+}
+
+/**
+ * Compile `Origin`, `OriginGlobs`, `OriginPatterns`, `AllowOriginFunc` and `DenyOrigin` once,
+ * for reuse with `LoadWithMatcher`. An invalid config is returned as error.
+ */
+func CompileMatcher(config Config) (*OriginMatcher, error) {
+	merge(_config, &config)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return compileMatcher(clone(config)), nil
+}
+
+func compileMatcher(config Config) *OriginMatcher {
+	m := &OriginMatcher{origins: newOriginMatcher(config)}
+	m.source.use(config)
+	if len(config.DenyOrigin) > 0 {
+		m.denied = newOriginMatcher(Config{Origin: config.DenyOrigin})
+	}
+	return m
+}
+
+/**
+ * Take the origin matcher fields of source, the ones `CompileMatcher` compiles
+ */
+func (c *Config) use(source Config) {
+	c.Origin = source.Origin
+	c.OriginGlobs = source.OriginGlobs
+	c.OriginPatterns = source.OriginPatterns
+	c.OriginRegexes = source.OriginRegexes
+	c.AllowOriginFunc = source.AllowOriginFunc
+	c.DenyOrigin = source.DenyOrigin
+}
+
+/**
+ * Check whether origin is allowed and not denied
+ */
+func (m *OriginMatcher) Match(origin string) bool {
+	if m.denied != nil && m.denied.match(origin) {
+		return false
+	}
+	return m.origins.match(origin)
+}
@@ -0,0 +1,65 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"reflect"
+)
+
+/**
+ * Layer override on top of base, e.g. per environment settings over shared ones
+ *
+ * Every non-zero field of override wins, slices included: a nil slice keeps the base value,
+ * an empty one replaces it. A bool or number can't be reset to zero by an override.
+ * The result shares no slice with either config.
+ */
+func Merge(base Config, override Config) Config {
+	return layer(base, override, false)
+}
+
+/**
+ * Like `Merge`, but slices of override are appended to those of base. String lists
+ * (`Origin`, `Methods`, `Headers`, ...) are unioned, values already in base are skipped.
+ */
+func MergeAppend(base Config, override Config) Config {
+	return layer(base, override, true)
+}
+
+func layer(base Config, override Config, appendSlices bool) Config {
+	out := reflect.ValueOf(&base).Elem()
+	over := reflect.ValueOf(override)
+	for i := 0; i < over.NumField(); i++ {
+		f := over.Field(i)
+		switch {
+		case f.IsZero():
+			// unset in override, base is kept
+		case appendSlices && f.Kind() == reflect.Slice:
+			out.Field(i).Set(union(out.Field(i), f))
+		default:
+			out.Field(i).Set(f)
+		}
+	}
+	return clone(base)
+}
+
+/**
+ * Append b to a, skipping strings already present
+ */
+func union(a reflect.Value, b reflect.Value) reflect.Value {
+	if a.Len() == 0 {
+		return b
+	}
+	if strs, ok := a.Interface().([]string); ok {
+		out := append([]string(nil), strs...)
+		for _, v := range b.Interface().([]string) {
+			if !hasMatch(out, v) {
+				out = append(out, v)
+			}
+		}
+		return reflect.ValueOf(out)
+	}
+	return reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(a.Type(), 0, a.Len()+b.Len()), a), b)
+}
@@ -0,0 +1,92 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	base := Config{
+		Origin:        []string{"https://app.com"},
+		Methods:       []string{"GET", "POST"},
+		ExposeHeaders: []string{"X-Total-Count"},
+		Credentials:   true,
+		MaxAge:        time.Hour,
+	}
+	override := Config{
+		Origin:         []string{"https://staging.app.com"},
+		ExposeHeaders:  []string{},
+		MaxAge:         time.Minute,
+		ResourcePolicy: "same-site",
+	}
+	got := Merge(base, override)
+	want := Config{
+		Origin:         []string{"https://staging.app.com"},
+		Methods:        []string{"GET", "POST"},
+		ExposeHeaders:  []string{},
+		Credentials:    true,
+		MaxAge:         time.Minute,
+		ResourcePolicy: "same-site",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merge = %+v, want %+v", got, want)
+	}
+
+	// zero values of override never reset base
+	if got = Merge(base, Config{}); !reflect.DeepEqual(got, base) {
+		t.Errorf("empty override = %+v", got)
+	}
+	// nor do the configs share slices with the result
+	got.Methods[0] = "DELETE"
+	if base.Methods[0] != "GET" {
+		t.Error("result shares slices with base")
+	}
+}
+
+func TestMergeAppend(t *testing.T) {
+	base := Config{
+		Origin:  []string{"https://app.com"},
+		Methods: []string{"GET", "POST"},
+		Headers: []string{"Content-Type"},
+	}
+	override := Config{
+		Origin:  []string{"https://admin.app.com", "https://app.com"},
+		Methods: []string{"POST", "PUT"},
+		Headers: []string{"X-Request-Id"},
+	}
+
+	replaced, appended := Merge(base, override), MergeAppend(base, override)
+	for _, c := range []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"replace origin", replaced.Origin, []string{"https://admin.app.com", "https://app.com"}},
+		{"replace methods", replaced.Methods, []string{"POST", "PUT"}},
+		{"replace headers", replaced.Headers, []string{"X-Request-Id"}},
+		// unioned in order, values of base first
+		{"append origin", appended.Origin, []string{"https://app.com", "https://admin.app.com"}},
+		{"append methods", appended.Methods, []string{"GET", "POST", "PUT"}},
+		{"append headers", appended.Headers, []string{"Content-Type", "X-Request-Id"}},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// other slices are appended as they are
+	rules := MergeAppend(Config{Rules: []Rule{{PathPrefix: "/a"}}}, Config{Rules: []Rule{{PathPrefix: "/a"}, {PathPrefix: "/b"}}}).Rules
+	if len(rules) != 3 {
+		t.Errorf("rules = %+v", rules)
+	}
+	// a nil slice of base takes the override as is
+	if got := MergeAppend(Config{}, override).Methods; !reflect.DeepEqual(got, override.Methods) {
+		t.Errorf("append to nil = %v", got)
+	}
+}
@@ -0,0 +1,353 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+/**
+ * Origin matcher compiled once at `Load`
+ *
+ * Exact origins are kept in a map, wildcard subdomain origins (`https://*.example.com`)
+ * are stored in a domain-suffix trie, so matching costs O(labels) rather than O(rules).
+ *
+ * Matchers are evaluated in a fixed order and the first allow wins:
+ * exact → wildcard → glob → regex → validated regex → func
+ */
+type originMatcher struct {
+	all bool
+	// `self` entry, the server origin of the request, see `isSelf`
+	self  bool
+	exact map[string]bool
+	// exact entries in config order, normalized
+	list []string
+	// scheme and host of `scheme://host:*` entries, allowed on any port
+	anyPort   map[string]bool
+	wildcards *trieNode
+	globs     []glob
+	patterns  []*regexp.Regexp
+	regexes   []OriginRegexWithValidator
+	fn        func(origin string) bool
+}
+
+/**
+ * Node of domain-suffix trie, labels are stored from right to left
+ */
+type trieNode struct {
+	children map[string]*trieNode
+	// scheme and port of wildcard rules ending at this node, both must match exactly
+	rules map[string]bool
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[string]*trieNode)}
+}
+
+/**
+ * Add wildcard rule, host is given without the leading `*.`
+ */
+func (n *trieNode) insert(host string, key string) {
+	labels := strings.Split(host, ".")
+	node := n
+	for i := len(labels) - 1; i >= 0; i-- {
+		child, ok := node.children[labels[i]]
+		if !ok {
+			child = newTrieNode()
+			node.children[labels[i]] = child
+		}
+		node = child
+	}
+	if node.rules == nil {
+		node.rules = make(map[string]bool)
+	}
+	node.rules[key] = true
+}
+
+/**
+ * Host matches, if any rule ends at a node while at least one label is left
+ */
+func (n *trieNode) match(host string, scheme string, port string) bool {
+	key, anyKey := scheme+":"+port, scheme+":*"
+	node := n
+	end := len(host)
+	for end > 0 {
+		start := strings.LastIndexByte(host[:end], '.') + 1
+		child, ok := node.children[host[start:end]]
+		if !ok {
+			return false
+		}
+		node = child
+		if start == 0 {
+			return false
+		}
+		end = start - 1
+		if node.rules[key] || node.rules[anyKey] {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Origin contains CR, LF or any other control character
+ */
+func hasControlChar(origin string) bool {
+	for i := 0; i < len(origin); i++ {
+		if origin[i] < 0x20 || origin[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Split origin into lower case scheme and host, and port
+ *
+ * IPv6 hosts keep their brackets, e.g. `http://[::1]:3000` gives `[::1]` and `3000`.
+ * A trailing dot is dropped, `https://example.com.` gives `example.com`.
+ * Origins never carry userinfo or a path, such values are not parsed.
+ */
+func parseOrigin(origin string) (scheme string, host string, port string, ok bool) {
+	i := strings.Index(origin, "://")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	// scheme and host are case-insensitive
+	scheme, host = strings.ToLower(origin[:i]), strings.ToLower(origin[i+3:])
+	if strings.HasPrefix(host, "[") {
+		j := strings.IndexByte(host, ']')
+		if j < 0 {
+			return "", "", "", false
+		}
+		rest := host[j+1:]
+		host = host[:j+1]
+		if rest != "" {
+			if rest[0] != ':' {
+				return "", "", "", false
+			}
+			port = rest[1:]
+		}
+	} else if j := strings.LastIndexByte(host, ':'); j >= 0 {
+		host, port = host[:j], host[j+1:]
+	}
+	// a single trailing dot of a fully qualified name is the same host
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || strings.ContainsAny(host, "@/") {
+		return "", "", "", false
+	}
+	return scheme, host, port, true
+}
+
+/**
+ * Default port of scheme is dropped, `https://example.com:443` is `https://example.com`
+ */
+func isDefaultPort(scheme string, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+/**
+ * Normalize origin for matching, lower cases it and drops default port and trailing dot of host
+ */
+func normalizeOrigin(origin string) string {
+	scheme, host, port, ok := parseOrigin(origin)
+	if !ok {
+		return strings.ToLower(origin)
+	}
+	if port == "" || isDefaultPort(scheme, port) {
+		return scheme + "://" + host
+	}
+	return scheme + "://" + host + ":" + port
+}
+
+func newOriginMatcher(config Config) *originMatcher {
+	m := &originMatcher{
+		exact:     make(map[string]bool),
+		anyPort:   make(map[string]bool),
+		wildcards: newTrieNode(),
+		patterns:  config.OriginPatterns,
+		regexes:   config.OriginRegexes,
+		fn:        config.AllowOriginFunc,
+	}
+	for _, g := range config.OriginGlobs {
+		m.globs = append(m.globs, compileGlob(normalizeOrigin(g)))
+	}
+	for _, o := range config.Origin {
+		if o == "*" {
+			m.all = true
+			continue
+		}
+		if o == "self" {
+			m.self = true
+			continue
+		}
+		scheme, host, port, ok := parseOrigin(o)
+		if ok && isDefaultPort(scheme, port) {
+			port = ""
+		}
+		if ok && strings.HasPrefix(host, "*.") {
+			m.wildcards.insert(host[2:], scheme+":"+port)
+			continue
+		}
+		if ok && port == "*" {
+			m.anyPort[scheme+"://"+host] = true
+			continue
+		}
+		if n := normalizeOrigin(o); !m.exact[n] {
+			m.exact[n] = true
+			m.list = append(m.list, n)
+		}
+	}
+	return m
+}
+
+/**
+ * Check whether origin is allowed, case-insensitively except for patterns and func
+ */
+func (m *originMatcher) match(origin string) bool {
+	return m.source(origin) != MatchNone
+}
+
+/**
+ * Matcher which allows origin, `MatchNone` if none
+ */
+func (m *originMatcher) source(origin string) MatchSource {
+	if m.all {
+		return MatchAll
+	}
+	// exact entries and globs see the origin lower cased, without default port and trailing dot
+	normalized := normalizeOrigin(origin)
+	if m.exact[normalized] {
+		return MatchExact
+	}
+	if scheme, host, port, ok := parseOrigin(origin); ok {
+		if isDefaultPort(scheme, port) {
+			port = ""
+		}
+		if m.anyPort[scheme+"://"+host] {
+			return MatchAnyPort
+		}
+		if m.wildcards.match(host, scheme, port) {
+			return MatchWildcard
+		}
+	}
+	for _, g := range m.globs {
+		if g.match(normalized) {
+			return MatchGlob
+		}
+	}
+	for _, p := range m.patterns {
+		if p.MatchString(origin) {
+			return MatchPattern
+		}
+	}
+	for _, r := range m.regexes {
+		if r.match(origin) {
+			return MatchRegex
+		}
+	}
+	if m.fn != nil && m.fn(origin) {
+		return MatchFunc
+	}
+	return MatchNone
+}
+
+/**
+ * Pattern matches and the validator accepts its named captures, unnamed groups are left out
+ */
+func (r OriginRegexWithValidator) match(origin string) bool {
+	sub := r.Pattern.FindStringSubmatch(origin)
+	if sub == nil {
+		return false
+	}
+	if r.Validate == nil {
+		return true
+	}
+	captures := make(map[string]string)
+	for i, name := range r.Pattern.SubexpNames() {
+		if name != "" {
+			captures[name] = sub[i]
+		}
+	}
+	return r.Validate(captures)
+}
+
+/**
+ * Origin of the server, derived from the request's TLS state and Host
+ */
+func serverOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+/**
+ * Origin host is a SAN of the verified client certificate, wildcard SANs match one label
+ */
+func clientCertAllows(r *http.Request, origin string) bool {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	_, host, _, ok := parseOrigin(origin)
+	return ok && r.TLS.VerifiedChains[0][0].VerifyHostname(host) == nil
+}
+
+/**
+ * Origin of the server, `SelfOrigin` or else derived from the request
+ */
+func selfOrigin(r *http.Request, config Config) string {
+	if config.SelfOrigin != "" {
+		return config.SelfOrigin
+	}
+	return serverOrigin(r)
+}
+
+/**
+ * Origin is the server origin, see `selfOrigin`
+ */
+func isSelf(r *http.Request, origin string, config Config) bool {
+	return normalizeOrigin(origin) == normalizeOrigin(selfOrigin(r, config))
+}
+
+/**
+ * Cross-check `Sec-Fetch-Site` against `Origin`
+ *
+ * This is a heuristic: `same-origin` requires the origin to equal the server origin,
+ * `same-site` only requires the same scheme and the same last two host labels, as no
+ * public suffix list is consulted. Other values are always consistent. The server origin
+ * is resolved as for `self`, so behind a proxy `SelfOrigin` must be set.
+ */
+func secFetchConsistent(r *http.Request, origin string, config Config) bool {
+	server := selfOrigin(r, config)
+	switch r.Header.Get(headerSecFetchSite) {
+	case "same-origin":
+		return normalizeOrigin(origin) == normalizeOrigin(server)
+	case "same-site":
+		scheme, host, _, ok := parseOrigin(origin)
+		sScheme, sHost, _, sOk := parseOrigin(server)
+		return ok && sOk && scheme == sScheme && site(host) == site(sHost)
+	}
+	return true
+}
+
+/**
+ * Last two labels of host
+ */
+func site(host string) string {
+	host = strings.ToLower(host)
+	i := strings.LastIndexByte(host, '.')
+	if i <= 0 {
+		return host
+	}
+	if j := strings.LastIndexByte(host[:i], '.'); j >= 0 {
+		return host[j+1:]
+	}
+	return host
+}
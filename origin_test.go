@@ -0,0 +1,500 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckSecFetchSite(t *testing.T) {
+	m := mustLoad(t, Config{CheckSecFetchSite: true})
+	cases := []struct {
+		site   string
+		origin string
+		err    error
+	}{
+		{"same-origin", "http://api.example.com", nil},
+		{"same-origin", "https://evil.com", OriginNotAllowed},
+		{"same-site", "http://www.example.com", nil},
+		{"same-site", "http://www.evil.com", OriginNotAllowed},
+		{"cross-site", "https://evil.com", nil},
+	}
+	for _, c := range cases {
+		w := do(m, request("GET", "http://api.example.com/", headerOrigin, c.origin, headerSecFetchSite, c.site))
+		if w.err != c.err {
+			t.Errorf("%s from %s: err = %v, want %v", c.site, c.origin, w.err, c.err)
+		}
+	}
+
+	// behind a proxy the request host is an internal one, the configured origin is checked instead
+	proxied := mustLoad(t, Config{CheckSecFetchSite: true, SelfOrigin: "https://api.example.com"})
+	r := request("GET", "http://backend:8080/", headerOrigin, "https://api.example.com", headerSecFetchSite, "same-origin")
+	if w := do(proxied, r); w.err != nil {
+		t.Errorf("self origin: err = %v", w.err)
+	}
+	r = request("GET", "http://backend:8080/", headerOrigin, "http://backend:8080", headerSecFetchSite, "same-origin")
+	if w := do(proxied, r); w.err != OriginNotAllowed {
+		t.Errorf("request host: err = %v", w.err)
+	}
+}
+
+func TestListAllowOrigin(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:          []string{"https://a.com", "self", "https://*.b.com", "HTTPS://C.com:443"},
+		OriginGlobs:     []string{"https://*-preview.d.com"},
+		ListAllowOrigin: true,
+		Rules:           []Rule{{PathPrefix: "/public", OriginPolicy: OriginPolicy{Origin: []string{"*", "https://e.com"}}}},
+		OriginsByHost:   map[string][]string{"api.f.com": {"https://f.com"}},
+	})
+	cases := []struct {
+		target string
+		origin string
+		want   string
+	}{
+		{"/", "https://x.b.com", "https://a.com, https://c.com"},
+		{"/public", "https://any.com", "https://e.com"},
+		{"http://api.f.com/", "https://f.com", "https://f.com"},
+	}
+	for _, c := range cases {
+		w := do(m, request("GET", c.target, headerOrigin, c.origin))
+		if w.err != nil {
+			t.Errorf("%s: err = %v", c.target, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, c.want)
+	}
+
+	// browsers get the standard header
+	w := do(m, request("GET", "/", headerOrigin, "https://x.b.com", headerSecFetchMode, "cors"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://x.b.com")
+	// nothing exact to list, the usual value is kept
+	w = serve(t, Config{ListAllowOrigin: true}, request("GET", "/", headerOrigin, "https://x.com"))
+	expectHeader(t, w.header, headerAllowOrigin, "*")
+}
+
+func TestReflectRequestCasing(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://Example.com"}})
+	for _, origin := range []string{"https://example.com", "https://EXAMPLE.com", "HTTPS://example.COM"} {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		if w.err != nil {
+			t.Errorf("%s: err = %v", origin, w.err)
+		}
+		// the request origin, never the configured casing
+		expectHeader(t, w.header, headerAllowOrigin, origin)
+	}
+}
+
+func TestWildcardTrie(t *testing.T) {
+	m := newOriginMatcher(Config{Origin: append(wildcardOrigins(300), "http://*.local.test:8080", "https://*.any.test:*")})
+	cases := map[string]bool{
+		"https://api.tenant42.example.com":   true,
+		"https://a.b.tenant299.example.com":  true,
+		"https://tenant42.example.com":       false,
+		"http://api.tenant42.example.com":    false,
+		"https://api.tenant300.example.com":  false,
+		"https://api.tenant42.example.com:8": false,
+		"http://app.local.test:8080":         true,
+		"http://app.local.test":              false,
+		"https://app.any.test:9443":          true,
+	}
+	for origin, want := range cases {
+		if got := m.match(origin); got != want {
+			t.Errorf("%s: %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestDenyOrigin(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:     []string{"https://*.example.com"},
+		DenyOrigin: []string{"https://evil.example.com", "https://*.staging.example.com"},
+	})
+	cases := map[string]error{
+		"https://app.example.com":       nil,
+		"https://api.example.com":       nil,
+		"https://evil.example.com":      OriginNotAllowed,
+		"https://EVIL.example.com":      OriginNotAllowed,
+		"https://a.staging.example.com": OriginNotAllowed,
+	}
+	for origin, want := range cases {
+		if w := do(m, request("GET", "/", headerOrigin, origin)); w.err != want {
+			t.Errorf("%s: err = %v, want %v", origin, w.err, want)
+		}
+	}
+}
+
+func TestIPOrigins(t *testing.T) {
+	m := newOriginMatcher(Config{Origin: []string{"http://192.168.1.5:8080", "http://[::1]:3000", "http://[::1]:*", "http://localhost:*"}})
+	cases := map[string]bool{
+		"http://192.168.1.5:8080":  true,
+		"http://192.168.1.5:8081":  false,
+		"http://192.168.1.50:8080": false,
+		"http://[::1]:3000":        true,
+		"http://[::1]:5173":        true,
+		"http://[::1]":             true,
+		"http://[::2]:3000":        false,
+		"http://localhost:5173":    true,
+		"https://[::1]:3000":       false,
+	}
+	for origin, want := range cases {
+		if got := m.match(origin); got != want {
+			t.Errorf("%s: %v, want %v", origin, got, want)
+		}
+	}
+
+	scheme, host, port, ok := parseOrigin("http://[2001:db8::1]:8443")
+	if !ok || scheme != "http" || host != "[2001:db8::1]" || port != "8443" {
+		t.Errorf("parsed %q %q %q %v", scheme, host, port, ok)
+	}
+	if _, _, _, ok := parseOrigin("http://[::1"); ok {
+		t.Error("unterminated IPv6 literal parsed")
+	}
+}
+
+func TestDefaultPorts(t *testing.T) {
+	m := newOriginMatcher(Config{Origin: []string{"https://example.com", "http://example.com:80", "https://api.example.com:8443"}})
+	cases := map[string]bool{
+		"https://example.com:443":      true,
+		"http://example.com":           true,
+		"http://example.com:80":        true,
+		"https://example.com:8443":     false,
+		"https://api.example.com:8443": true,
+		"https://api.example.com":      false,
+		"http://example.com:443":       false,
+	}
+	for origin, want := range cases {
+		if got := m.match(origin); got != want {
+			t.Errorf("%s: %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestWildcardScheme(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://*.example.com"}})
+	if w := do(m, request("GET", "/", headerOrigin, "https://foo.example.com")); w.err != nil {
+		t.Errorf("https: err = %v", w.err)
+	}
+	for _, origin := range []string{"http://foo.example.com", "wss://foo.example.com", "https://foo.example.com:8443"} {
+		if w := do(m, request("GET", "/", headerOrigin, origin)); w.err != OriginNotAllowed {
+			t.Errorf("%s: err = %v", origin, w.err)
+		}
+	}
+}
+
+func TestNullOrigin(t *testing.T) {
+	for _, credentials := range []bool{false, true} {
+		m := mustLoad(t, Config{Origin: []string{"null"}, Credentials: credentials})
+		want := ""
+		if credentials {
+			want = "true"
+		}
+
+		w := do(m, request("GET", "/", headerOrigin, "null"))
+		if w.err != nil {
+			t.Fatalf("credentials %v: err = %v", credentials, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "null")
+		expectHeader(t, w.header, headerAllowCredentials, want)
+		expectHeader(t, w.header, headerVary, headerOrigin)
+
+		w = do(m, preflightRequest("/", "null", "PUT", ""))
+		if w.err != nil || w.status != 204 {
+			t.Errorf("credentials %v, preflight: err = %v, status = %d", credentials, w.err, w.status)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "null")
+		expectHeader(t, w.header, headerAllowCredentials, want)
+
+		if w = do(m, request("GET", "/", headerOrigin, "https://app.com")); w.err != OriginNotAllowed {
+			t.Errorf("credentials %v, other origin: err = %v", credentials, w.err)
+		}
+	}
+
+	// only a listed `null` allows opaque origins
+	if w := serve(t, Config{Origin: []string{"https://app.com"}}, request("GET", "/", headerOrigin, "null")); w.err != OriginNotAllowed {
+		t.Errorf("unlisted: err = %v", w.err)
+	}
+}
+
+func TestOriginRewrite(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{"http://app.internal", "http://legacy.internal"},
+		OriginRewrite: func(origin string) string {
+			if origin == "http://legacy.internal" {
+				return ""
+			}
+			return "https://app.example.com"
+		},
+	})
+	w := do(m, request("GET", "/", headerOrigin, "http://app.internal"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.example.com")
+	expectHeader(t, w.header, headerVary, headerOrigin)
+
+	w = do(m, request("GET", "/", headerOrigin, "http://legacy.internal"))
+	if w.err != nil {
+		t.Errorf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "")
+
+	// a rewritten `*` config reflects instead of emitting the literal
+	w = serve(t, Config{OriginRewrite: func(string) string { return "https://public.com" }}, request("GET", "/", headerOrigin, "http://a.internal"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://public.com")
+}
+
+func TestCredentialsRequireHTTPS(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com", "http://app.com"}, Credentials: true, CredentialsRequireHTTPS: true})
+
+	w := do(m, request("GET", "/", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+
+	// the origin is still allowed, only without credentials
+	w = do(m, request("GET", "/", headerOrigin, "http://app.com"))
+	if w.err != nil {
+		t.Fatalf("http: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "http://app.com")
+	expectHeader(t, w.header, headerAllowCredentials, "")
+
+	// whatever is not https, opaque origins included
+	for _, origin := range []string{"null", "wss://app.com"} {
+		w = serve(t, Config{Origin: []string{origin}, Credentials: true, CredentialsRequireHTTPS: true}, request("GET", "/", headerOrigin, origin))
+		if w.err != nil {
+			t.Fatalf("%s: err = %v", origin, w.err)
+		}
+		expectHeader(t, w.header, headerAllowCredentials, "")
+	}
+	w = serve(t, Config{Origin: []string{"HTTPS://app.com"}, Credentials: true, CredentialsRequireHTTPS: true}, request("GET", "/", headerOrigin, "HTTPS://app.com"))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+
+	w = serve(t, Config{Origin: []string{"http://app.com"}, Credentials: true}, request("GET", "/", headerOrigin, "http://app.com"))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+}
+
+func TestControlCharactersInOrigin(t *testing.T) {
+	// the func allows everything, so only the guard can reject
+	m := mustLoad(t, Config{Origin: []string{}, AllowOriginFunc: func(string) bool { return true }})
+	for _, origin := range []string{"https://app.com\r\nSet-Cookie: a=b", "https://app.com\n", "https://a\x00pp.com", "https://app.com\x7f"} {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		if w.err != OriginNotAllowed {
+			t.Errorf("%q: err = %v", origin, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "")
+	}
+	if w := do(m, request("GET", "/", headerOrigin, "https://app.com")); w.err != nil {
+		t.Errorf("clean origin: err = %v", w.err)
+	}
+
+	w := serve(t, Config{ReportOnly: true}, request("GET", "/", headerOrigin, "https://app.com\r\nX: y"))
+	if w.err != nil {
+		t.Errorf("report only: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "")
+}
+
+func TestMaxOriginLength(t *testing.T) {
+	long := "https://" + strings.Repeat("a", 300) + ".com"
+	config := Config{Origin: []string{long}}
+	if w := serve(t, config, request("GET", "/", headerOrigin, long)); w.err != OriginNotAllowed {
+		t.Errorf("default limit: err = %v", w.err)
+	}
+	// the longest valid host with scheme and port fits the default
+	fits := "https://" + strings.Repeat("a", 249) + ".com:65535"
+	if w := serve(t, Config{Origin: []string{fits}}, request("GET", "/", headerOrigin, fits)); w.err != nil {
+		t.Errorf("longest host: err = %v", w.err)
+	}
+
+	config.MaxOriginLength = -1
+	if w := serve(t, config, request("GET", "/", headerOrigin, long)); w.err != nil {
+		t.Errorf("disabled: err = %v", w.err)
+	}
+	if w := serve(t, Config{Origin: []string{"https://example.com"}, MaxOriginLength: 18}, request("GET", "/", headerOrigin, "https://example.com")); w.err != OriginNotAllowed {
+		t.Errorf("custom limit: err = %v", w.err)
+	}
+}
+
+func TestTrailingDotOrigin(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://example.com", "https://dotted.com.", "https://*.example.org"}})
+	for _, origin := range []string{"https://example.com.", "https://example.com.:443", "https://dotted.com", "https://a.example.org."} {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		if w.err != nil {
+			t.Errorf("%s: err = %v", origin, w.err)
+		}
+		// the browser compares against what it sent
+		expectHeader(t, w.header, headerAllowOrigin, origin)
+	}
+	// only a single dot
+	if w := do(m, request("GET", "/", headerOrigin, "https://example.com..")); w.err != OriginNotAllowed {
+		t.Errorf("two dots: err = %v", w.err)
+	}
+}
+
+func TestReflectConcreteOrigin(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:          []string{"https://exact.com", "http://localhost:*", "https://*.wild.com"},
+		OriginGlobs:     []string{"https://*.glob-*.com"},
+		OriginPatterns:  []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.pattern\.com$`)},
+		OriginRegexes:   []OriginRegexWithValidator{{Pattern: regexp.MustCompile(`^https://(?P<team>\w+)\.regex\.com$`), Validate: func(c map[string]string) bool { return c["team"] == "core" }}},
+		AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".func.com") },
+		OriginResolver:  staticResolver{"https://app.resolver.com": true},
+		Credentials:     true,
+	})
+	for origin, source := range map[string]MatchSource{
+		"https://exact.com":         MatchExact,
+		"https://EXACT.com":         MatchExact,
+		"http://localhost:5173":     MatchAnyPort,
+		"https://a.b.wild.com":      MatchWildcard,
+		"https://a.glob-eu.com":     MatchGlob,
+		"https://pr-12.pattern.com": MatchPattern,
+		"https://core.regex.com":    MatchRegex,
+		"https://x.func.com":        MatchFunc,
+		"https://app.resolver.com":  MatchResolver,
+	} {
+		res, err := m.Evaluate(request("GET", "/", headerOrigin, origin))
+		if err != nil || res.Source != source {
+			t.Errorf("%s: source = %v, err = %v; want %v", origin, res.Source, err, source)
+			continue
+		}
+		expectHeader(t, res.Headers, headerAllowOrigin, origin)
+		expectHeader(t, res.Headers, headerVary, headerOrigin)
+	}
+}
+
+func TestAllowByClientCertSAN(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, AllowByClientCertSAN: true})
+	cert := &x509.Certificate{DNSNames: []string{"gateway.partner.com", "*.eu.partner.com"}}
+	withCert := func(origin string) *http.Request {
+		r := request("GET", "https://api.com/", headerOrigin, origin)
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	for origin, want := range map[string]bool{
+		"https://gateway.partner.com":      true,
+		"https://gateway.partner.com:8443": true,
+		"https://a.eu.partner.com":         true,
+		"https://a.b.eu.partner.com":       false,
+		"https://partner.com":              false,
+	} {
+		res, err := m.Evaluate(withCert(origin))
+		if got := err == nil; got != want {
+			t.Errorf("%s: allowed = %v, want %v", origin, got, want)
+		}
+		if want && res.Source != MatchClientCert {
+			t.Errorf("%s: source = %v", origin, res.Source)
+		}
+	}
+
+	// a certificate presented but not verified counts for nothing
+	r := request("GET", "https://api.com/", headerOrigin, "https://gateway.partner.com")
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if _, err := m.Evaluate(r); err != OriginNotAllowed {
+		t.Errorf("unverified: err = %v", err)
+	}
+	if w := serve(t, Config{Origin: []string{"https://app.com"}}, withCert("https://gateway.partner.com")); w.err != OriginNotAllowed {
+		t.Errorf("off by default: err = %v", w.err)
+	}
+}
+
+func TestReflectOriginVerbatim(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.example.com", "https://*.example.org"}, Credentials: true})
+	// the second casing must not be answered from the cached first one
+	for _, origin := range []string{"https://App.Example.com", "https://APP.EXAMPLE.COM", "https://app.example.com", "https://Shop.Example.ORG"} {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		if w.err != nil {
+			t.Errorf("%s: err = %v", origin, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, origin)
+	}
+}
+
+func TestOriginRegexWithValidator(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{},
+		OriginRegexes: []OriginRegexWithValidator{{
+			Pattern: regexp.MustCompile(`^https://pr-(?P<pr>[0-9]+)\.preview\.example\.com$`),
+			Validate: func(captures map[string]string) bool {
+				pr, err := strconv.Atoi(captures["pr"])
+				return err == nil && pr >= 100 && pr < 200
+			},
+		}},
+	})
+	for origin, want := range map[string]bool{
+		"https://pr-100.preview.example.com": true,
+		"https://pr-199.preview.example.com": true,
+		"https://pr-99.preview.example.com":  false,
+		"https://pr-200.preview.example.com": false,
+		"https://pr-x.preview.example.com":   false,
+	} {
+		res, err := m.Evaluate(request("GET", "/", headerOrigin, origin))
+		if got := err == nil; got != want {
+			t.Errorf("%s: allowed = %v, want %v", origin, got, want)
+		}
+		if want && res.Source != MatchRegex {
+			t.Errorf("%s: source = %v", origin, res.Source)
+		}
+	}
+}
+
+func TestSelfOrigin(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"self"}})
+	for _, c := range []struct {
+		target string
+		origin string
+		err    error
+	}{
+		{"http://api.example.com/", "http://api.example.com", nil},
+		{"http://api.example.com:8080/", "http://api.example.com:8080", nil},
+		{"https://api.example.com/", "https://api.example.com", nil},
+		{"https://api.example.com/", "https://api.example.com:443", nil},
+		{"https://api.example.com/", "http://api.example.com", OriginNotAllowed},
+		{"http://api.example.com/", "http://app.example.com", OriginNotAllowed},
+		{"http://api.example.com/", "http://api.example.com:8080", OriginNotAllowed},
+	} {
+		// https targets carry a TLS state
+		if w := do(m, request("GET", c.target, headerOrigin, c.origin)); w.err != c.err {
+			t.Errorf("%s to %s: err = %v, want %v", c.origin, c.target, w.err, c.err)
+		}
+	}
+
+	// behind a proxy the configured origin is the server origin
+	proxied := mustLoad(t, Config{Origin: []string{"self", "https://app.com"}, SelfOrigin: "https://api.example.com"})
+	if w := do(proxied, request("GET", "http://backend:8080/", headerOrigin, "https://api.example.com")); w.err != nil {
+		t.Errorf("self origin: err = %v", w.err)
+	}
+	if w := do(proxied, request("GET", "http://backend:8080/", headerOrigin, "http://backend:8080")); w.err != OriginNotAllowed {
+		t.Errorf("internal origin: err = %v", w.err)
+	}
+}
+
+func TestAllowCredentialsForPatterns(t *testing.T) {
+	config := Config{
+		Origin:         []string{"https://app.com", "https://*.example.com", "http://localhost:*"},
+		OriginGlobs:    []string{"https://*-preview.example.org"},
+		OriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.example\.net$`)},
+		Credentials:    true,
+	}
+	patterned := []string{"https://a.example.com", "http://localhost:5173", "https://x-preview.example.org", "https://pr-1.example.net"}
+
+	m := mustLoad(t, config)
+	for _, origin := range patterned {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		expectHeader(t, w.header, headerAllowOrigin, origin)
+		expectHeader(t, w.header, headerAllowCredentials, "")
+	}
+	// listed origins keep them
+	expectHeader(t, do(m, request("GET", "/", headerOrigin, "https://app.com")).header, headerAllowCredentials, "true")
+
+	config.AllowCredentialsForPatterns = true
+	m = mustLoad(t, config)
+	for _, origin := range patterned {
+		w := do(m, request("GET", "/", headerOrigin, origin))
+		expectHeader(t, w.header, headerAllowCredentials, "true")
+	}
+}
@@ -0,0 +1,93 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOverlappingPolicies(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{"https://*.partner.com"},
+		OriginPolicies: []OriginPolicy{
+			{Origin: []string{"https://*.partner.com"}, Methods: []string{"GET"}, MaxAge: time.Minute},
+			{Origin: []string{"https://api.partner.com"}, Methods: []string{"GET", "PUT"}},
+		},
+		Rules: []Rule{
+			{PathPrefix: "/api", OriginPolicy: OriginPolicy{Headers: []string{"X-Api"}}},
+			{PathPrefix: "/api/v2", OriginPolicy: OriginPolicy{Headers: []string{"X-V2"}}},
+		},
+	})
+
+	// the first policy wins, however specific the later one
+	w := do(m, preflightRequest("/", "https://api.partner.com", "GET", ""))
+	expectHeader(t, w.header, headerAllowOrigin, "https://api.partner.com")
+	expectHeader(t, w.header, headerAllowMethods, "GET")
+	expectHeader(t, w.header, headerMaxAge, "60")
+	if w = do(m, preflightRequest("/", "https://api.partner.com", "PUT", "")); w.err != MethodNotAllowed {
+		t.Errorf("shadowed policy: err = %v", w.err)
+	}
+	if got := m.MethodsFor("https://api.partner.com"); len(got) != 1 || got[0] != "GET" {
+		t.Errorf("methods for = %v", got)
+	}
+
+	// the first rule wins as well
+	w = do(m, preflightRequest("/api/v2/items", "https://api.partner.com", "GET", "X-Api"))
+	if w.err != nil {
+		t.Errorf("first rule: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/api/v2/items", "https://api.partner.com", "GET", "X-V2")); w.err != HeadersNotAllowed {
+		t.Errorf("shadowed rule: err = %v", w.err)
+	}
+}
+
+func TestMethodsFor(t *testing.T) {
+	m := mustLoad(t, Config{
+		Methods:        []string{"GET", "POST", "PUT"},
+		OriginPolicies: []OriginPolicy{{Origin: []string{"https://partner.com"}, Methods: []string{"GET"}}},
+	})
+	if got := m.MethodsFor("https://partner.com"); !reflect.DeepEqual(got, []string{"GET"}) {
+		t.Errorf("policy: methods = %v", got)
+	}
+	got := m.MethodsFor("https://other.com")
+	if !reflect.DeepEqual(got, []string{"GET", "POST", "PUT"}) {
+		t.Errorf("default: methods = %v", got)
+	}
+	// callers get a copy
+	got[0] = "DELETE"
+	if m.MethodsFor("https://other.com")[0] != "GET" {
+		t.Error("methods shared with the config")
+	}
+}
+
+func TestPolicyInheritance(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:         []string{"https://*.partner.com"},
+		Headers:        []string{"Content-Type", "X-Api-Key"},
+		MaxAge:         10 * time.Minute,
+		OriginPolicies: []OriginPolicy{{Origin: []string{"https://ro.partner.com"}, Methods: []string{"GET", "HEAD"}}},
+	})
+
+	w := do(m, preflightRequest("/", "https://ro.partner.com", "GET", "X-Api-Key"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET, HEAD")
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type, X-Api-Key")
+	expectHeader(t, w.header, headerMaxAge, "600")
+	if w = do(m, preflightRequest("/", "https://ro.partner.com", "PUT", "")); w.err != MethodNotAllowed {
+		t.Errorf("overridden methods: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/", "https://ro.partner.com", "GET", "X-Other")); w.err != HeadersNotAllowed {
+		t.Errorf("inherited headers: err = %v", w.err)
+	}
+
+	// other origins get the global methods
+	w = do(m, preflightRequest("/", "https://rw.partner.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
+}
@@ -0,0 +1,484 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestAdvertisedMethods(t *testing.T) {
+	m := mustLoad(t, Config{
+		Methods:           []string{"GET", "POST"},
+		AdvertisedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		OriginPolicies:    []OriginPolicy{{Origin: []string{"https://partner.com"}, Methods: []string{"GET"}}},
+		Rules:             []Rule{{PathPrefix: "/admin", OriginPolicy: OriginPolicy{Methods: []string{"GET", "DELETE"}}}},
+	})
+
+	w := do(m, preflightRequest("/", "https://app.com", "POST", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST, PUT, DELETE")
+	if w = do(m, preflightRequest("/", "https://app.com", "PUT", "")); w.err != MethodNotAllowed {
+		t.Errorf("advertised only: err = %v", w.err)
+	}
+
+	w = do(m, preflightRequest("/", "https://partner.com", "GET", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET")
+	w = do(m, preflightRequest("/admin", "https://app.com", "DELETE", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET, DELETE")
+}
+
+func TestWildcardRequestHeaders(t *testing.T) {
+	allowAll := Config{Origin: []string{"https://app.com"}, Headers: []string{"*"}}
+	w := serve(t, allowAll, preflightRequest("/", "https://app.com", "PUT", "*"))
+	if w.err != nil || w.status != 204 {
+		t.Fatalf("wildcard: err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "*")
+
+	// a lone `*` is no header name a list allows
+	if w = serve(t, Config{Headers: []string{"X-Custom"}}, preflightRequest("/", "https://app.com", "PUT", "*")); w.err != HeadersNotAllowed {
+		t.Errorf("list: err = %v", w.err)
+	}
+
+	// never `*` next to credentials, whichever way the headers are reflected
+	for name, config := range map[string]Config{
+		"wildcard":  {Origin: []string{"https://app.com"}, Headers: []string{"*"}, Credentials: true},
+		"requested": {Origin: []string{"https://app.com"}, AllowRequestedHeaders: true, Credentials: true},
+	} {
+		w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", "*"))
+		if w.err != nil || w.status != 204 {
+			t.Errorf("%s, credentials: err = %v, status = %d", name, w.err, w.status)
+		}
+		expectHeader(t, w.header, headerAllowCredentials, "true")
+		expectHeader(t, w.header, headerAllowHeaders, "")
+
+		w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", "*, X-Custom"))
+		expectHeader(t, w.header, headerAllowHeaders, "X-Custom")
+	}
+}
+
+func TestSetAllowHeader(t *testing.T) {
+	w := serve(t, Config{SetAllowHeader: true, OmitDefaultMethodsHeader: true}, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowMethods, "*")
+	expectHeader(t, w.header, headerAllow, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
+
+	w = serve(t, Config{Methods: []string{"GET", "PUT"}, SetAllowHeader: true, SplitHeaderLines: true}, preflightRequest("/", "https://app.com", "PUT", ""))
+	if w.status != 204 {
+		t.Errorf("status = %d", w.status)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET|PUT")
+	expectHeader(t, w.header, headerAllow, "GET|PUT")
+}
+
+func TestAutoAllowAuthorization(t *testing.T) {
+	policy := OriginPolicy{Headers: []string{"X-Upload-Id"}}
+	m := mustLoad(t, Config{
+		Headers:                []string{"Content-Type"},
+		Credentials:            true,
+		AutoAllowAuthorization: true,
+		OriginPolicies:         []OriginPolicy{{Origin: []string{"https://partner.com"}, Headers: []string{"X-Partner"}}},
+		Rules:                  []Rule{{PathPrefix: "/upload", OriginPolicy: policy}},
+	})
+
+	for _, r := range []struct{ path, origin, want string }{
+		{"/", "https://app.com", "Content-Type, Authorization"},
+		{"/", "https://partner.com", "X-Partner, Authorization"},
+		{"/upload", "https://app.com", "X-Upload-Id, Authorization"},
+	} {
+		w := do(m, preflightRequest(r.path, r.origin, "GET", "authorization"))
+		if w.err != nil {
+			t.Errorf("%s from %s: err = %v", r.path, r.origin, w.err)
+		}
+		expectHeader(t, w.header, headerAllowHeaders, r.want)
+	}
+	if len(policy.Headers) != 1 {
+		t.Errorf("caller's rule was changed: %v", policy.Headers)
+	}
+}
+
+func TestPreflightCredentials(t *testing.T) {
+	config := Config{Origin: []string{"https://app.com"}, Credentials: true}
+	preflight := preflightRequest("/", "https://app.com", "PUT", "")
+	for name, m := range map[string]*Middleware{"load": mustLoad(t, config), "preflight handler": mustNew(config, modePreflight)} {
+		w := do(m, preflight)
+		if w.status != 204 {
+			t.Errorf("%s: status = %d", name, w.status)
+		}
+		expectHeader(t, w.header, headerAllowCredentials, "true")
+	}
+
+	// the browser names the failing check, not the credentials
+	w := serve(t, config, preflightRequest("/", "https://app.com", "PURGE", ""))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+
+	w = serve(t, config, request("GET", "/", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+}
+
+func TestPreflightSkipsExposeHeaders(t *testing.T) {
+	called := false
+	config := Config{
+		ExposeHeaders:     []string{"X-Total-Count"},
+		ExposeHeadersFunc: func(ctx *rest.Context) []string { called = true; return nil },
+	}
+	for name, m := range map[string]*Middleware{"load": mustLoad(t, config), "preflight handler": mustNew(config, modePreflight)} {
+		w := do(m, preflightRequest("/", "https://app.com", "GET", ""))
+		if w.status != 204 || called {
+			t.Errorf("%s: status = %d, expose func called = %v", name, w.status, called)
+		}
+		expectHeader(t, w.header, headerExposeHeaders, "")
+	}
+}
+
+func TestSplitHeaderLines(t *testing.T) {
+	config := Config{Methods: []string{"GET", "PUT"}, Headers: []string{"Content-Type", "X-Request-Id"}, SplitHeaderLines: true}
+	w := serve(t, config, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET|PUT")
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type|X-Request-Id")
+
+	config.SplitHeaderLines = false
+	w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowMethods, "GET, PUT")
+}
+
+func TestMaxAgeFunc(t *testing.T) {
+	m := mustLoad(t, Config{
+		MaxAge: time.Hour,
+		MaxAgeFunc: func(ctx *rest.Context) time.Duration {
+			if strings.HasPrefix(ctx.Request.URL.Path, "/static") {
+				return 24 * time.Hour
+			}
+			return time.Minute
+		},
+		DebugHeaders: true,
+	})
+	for path, want := range map[string]string{"/static/app.js": "86400", "/api/feed": "60"} {
+		w := do(m, preflightRequest(path, "https://app.com", "GET", ""))
+		expectHeader(t, w.header, headerMaxAge, want)
+		expectHeader(t, w.header, headerDebugMaxAge, want)
+	}
+}
+
+func TestHeaderRejectionHeaders(t *testing.T) {
+	w := serve(t, Config{Origin: []string{"https://app.com"}, Credentials: true}, preflightRequest("/", "https://app.com", "PUT", "X-Secret"))
+	if w.err != HeadersNotAllowed || w.status != 403 {
+		t.Fatalf("err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+	expectHeader(t, w.header, headerVary, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	// nothing is granted by a rejected preflight
+	expectHeader(t, w.header, headerAllowMethods, "")
+	expectHeader(t, w.header, headerAllowHeaders, "")
+	expectHeader(t, w.header, headerMaxAge, "")
+}
+
+func TestPreflightHandler(t *testing.T) {
+	m := mustNew(Config{Origin: []string{"https://app.com"}, Methods: []string{"GET", "PUT"}}, modePreflight)
+
+	w := do(m, preflightRequest("/items", "https://app.com", "PUT", "Content-Type"))
+	if w.err != nil || w.status != 204 || !w.ended {
+		t.Fatalf("err = %v, status = %d, ended = %v", w.err, w.status, w.ended)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET, PUT")
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type")
+
+	// an OPTIONS route is known to be CORS
+	w = do(m, request("OPTIONS", "/items", headerOrigin, "https://app.com"))
+	if w.err != nil || w.status != 204 || !w.ended {
+		t.Errorf("without request method: err = %v, status = %d, ended = %v", w.err, w.status, w.ended)
+	}
+	if w = do(m, preflightRequest("/items", "https://app.com", "DELETE", "")); w.err != MethodNotAllowed {
+		t.Errorf("method: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/items", "https://evil.com", "PUT", "")); w.err != OriginNotAllowed {
+		t.Errorf("origin: err = %v", w.err)
+	}
+}
+
+func TestSimpleHandler(t *testing.T) {
+	m := mustNew(Config{Origin: []string{"https://app.com"}, Credentials: true, ExposeHeaders: []string{"X-Total-Count"}}, modeSimple)
+
+	for _, method := range []string{"GET", "POST"} {
+		w := do(m, request(method, "/items", headerOrigin, "https://app.com"))
+		if w.err != nil || w.ended {
+			t.Errorf("%s: err = %v, ended = %v", method, w.err, w.ended)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+		expectHeader(t, w.header, headerAllowCredentials, "true")
+		expectHeader(t, w.header, headerExposeHeaders, "X-Total-Count")
+	}
+
+	// preflights are left to another handler
+	for _, origin := range []string{"https://app.com", "https://evil.com"} {
+		if w := do(m, preflightRequest("/items", origin, "PUT", "")); len(w.calls) != 0 {
+			t.Errorf("preflight from %s: calls = %v", origin, w.calls)
+		}
+	}
+	if w := do(m, request("POST", "/items", headerOrigin, "https://evil.com")); w.err != OriginNotAllowed {
+		t.Errorf("rejection: err = %v", w.err)
+	}
+}
+
+func TestMalformedPreflight(t *testing.T) {
+	m := mustLoad(t, Config{Headers: []string{"Content-Type"}})
+	for _, c := range []struct {
+		name    string
+		method  string
+		headers string
+	}{
+		{"empty method", "", "Content-Type"},
+		{"method with space", "GET POST", ""},
+		{"commas only", "PUT", ", ,"},
+		{"header with space", "PUT", "content type"},
+		{"header with colon", "PUT", "X-A:b"},
+	} {
+		r := preflightRequest("/", "https://app.com", c.method, c.headers)
+		w := do(m, r)
+		if w.err != MalformedPreflight || w.status != 400 {
+			t.Errorf("%s: err = %v, status = %d", c.name, w.err, w.status)
+		}
+		expectHeader(t, w.header, headerAllowMethods, "")
+	}
+
+	// policy rejections stay 403
+	if w := do(m, preflightRequest("/", "https://app.com", "PUT", "X-Other")); w.err != HeadersNotAllowed || w.status != 403 {
+		t.Errorf("policy: err = %v, status = %d", w.err, w.status)
+	}
+}
+
+func TestBareOptionsBehavior(t *testing.T) {
+	bare := request("OPTIONS", "/items", headerOrigin, "https://app.com")
+	config := Config{Origin: []string{"https://app.com"}}
+
+	// simple: allow-origin is set and the handlers answer
+	w := serve(t, config, bare)
+	if w.ended || w.status != 0 {
+		t.Errorf("simple: ended = %v, status = %d", w.ended, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowMethods, "")
+
+	config.BareOptionsBehavior = BareOptionsRespond
+	w = serve(t, config, bare)
+	if !w.ended || w.status != 204 {
+		t.Errorf("respond: ended = %v, status = %d", w.ended, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+
+	config.BareOptionsBehavior = BareOptionsPassThrough
+	if w = serve(t, config, bare); len(w.calls) != 0 {
+		t.Errorf("pass through: calls = %v", w.calls)
+	}
+	// a disallowed origin passes through as well
+	if w = serve(t, config, request("OPTIONS", "/items", headerOrigin, "https://evil.com")); len(w.calls) != 0 {
+		t.Errorf("pass through, disallowed: calls = %v", w.calls)
+	}
+	// the behavior only applies without a request method
+	if w = serve(t, config, preflightRequest("/items", "https://app.com", "PUT", "")); w.status != 204 {
+		t.Errorf("preflight: status = %d", w.status)
+	}
+
+	config.SpecCompliant = true
+	if w = serve(t, config, bare); w.ended || w.header.Get(headerAllowOrigin) != "https://app.com" {
+		t.Errorf("spec compliant: ended = %v, headers = %v", w.ended, w.header)
+	}
+}
+
+func TestLenientHeaders(t *testing.T) {
+	config := Config{Headers: []string{"Content-Type", "X-Request-Id"}}
+	r := preflightRequest("/", "https://app.com", "PUT", "x-request-id, x-new-feature")
+
+	w := serve(t, config, r)
+	if w.err != HeadersNotAllowed {
+		t.Errorf("strict: err = %v", w.err)
+	}
+
+	config.LenientHeaders = true
+	w = serve(t, config, r)
+	if w.err != nil || w.status != 204 {
+		t.Fatalf("lenient: err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "x-request-id")
+	// nothing in common still answers, without any header allowed
+	w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", "x-new-feature"))
+	if w.err != nil {
+		t.Errorf("lenient, none allowed: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "")
+}
+
+func TestCanonicalizeReflectedHeaders(t *testing.T) {
+	config := Config{AllowRequestedHeaders: true}
+	r := preflightRequest("/", "https://app.com", "PUT", "x-custom, content-type, x-request-id")
+
+	w := serve(t, config, r)
+	expectHeader(t, w.header, headerAllowHeaders, "x-custom, content-type, x-request-id")
+
+	config.CanonicalizeReflectedHeaders = true
+	w = serve(t, config, r)
+	expectHeader(t, w.header, headerAllowHeaders, "X-Custom, Content-Type, X-Request-Id")
+
+	// lenient intersections are reflected as well
+	w = serve(t, Config{Headers: []string{"X-Custom"}, LenientHeaders: true, CanonicalizeReflectedHeaders: true}, r)
+	expectHeader(t, w.header, headerAllowHeaders, "X-Custom")
+}
+
+func TestMaxRequestHeaders(t *testing.T) {
+	names := func(n int) string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = "x-h" + strconv.Itoa(i)
+		}
+		return strings.Join(list, ",")
+	}
+	config := Config{AllowRequestedHeaders: true}
+
+	if w := serve(t, config, preflightRequest("/", "https://app.com", "PUT", names(64))); w.err != nil {
+		t.Errorf("64 headers: err = %v", w.err)
+	}
+	w := serve(t, config, preflightRequest("/", "https://app.com", "PUT", names(65)))
+	if w.err != HeadersNotAllowed {
+		t.Errorf("65 headers: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "")
+
+	config.MaxRequestHeaders = 2
+	if w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", names(3))); w.err != HeadersNotAllowed {
+		t.Errorf("custom limit: err = %v", w.err)
+	}
+	config.MaxRequestHeaders = -1
+	if w = serve(t, config, preflightRequest("/", "https://app.com", "PUT", names(1000))); w.err != nil {
+		t.Errorf("disabled: err = %v", w.err)
+	}
+}
+
+func TestPrivateNetworkOrigins(t *testing.T) {
+	config := Config{Origin: []string{"https://app.com", "https://intranet.corp.com"}, PrivateNetworkOrigins: []string{"https://intranet.corp.com"}}
+	pna := func(origin string) *http.Request {
+		r := preflightRequest("/", origin, "GET", "")
+		r.Header.Set(headerRequestPrivateNetwork, "true")
+		return r
+	}
+	m := mustLoad(t, config)
+
+	w := do(m, pna("https://intranet.corp.com"))
+	expectHeader(t, w.header, headerAllowPrivateNetwork, "true")
+	expectHeader(t, w.header, headerVary, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers, Access-Control-Request-Private-Network")
+
+	// generally allowed, but not trusted with the private network
+	w = do(m, pna("https://app.com"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowPrivateNetwork, "")
+
+	// only when asked for
+	w = do(m, preflightRequest("/", "https://intranet.corp.com", "GET", ""))
+	expectHeader(t, w.header, headerAllowPrivateNetwork, "")
+
+	// a subset of the allowed origins, it allows none on its own
+	if w = serve(t, Config{Origin: []string{"https://app.com"}, PrivateNetworkOrigins: []string{"https://nas.local"}}, pna("https://nas.local")); w.err != OriginNotAllowed {
+		t.Errorf("unlisted: err = %v", w.err)
+	}
+
+	config.PrivateNetworkOrigins = nil
+	w = serve(t, config, pna("https://intranet.corp.com"))
+	expectHeader(t, w.header, headerAllowPrivateNetwork, "")
+}
+
+func TestOmitDefaultMethodsHeader(t *testing.T) {
+	config := Config{OmitDefaultMethodsHeader: true}
+	r := preflightRequest("/", "https://app.com", "PATCH", "")
+
+	w := serve(t, config, r)
+	expectHeader(t, w.header, headerAllowMethods, "*")
+
+	// `*` is literal with credentials, the requested method is echoed
+	config.Credentials = true
+	w = serve(t, config, r)
+	if w.err != nil {
+		t.Fatalf("credentials: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "PATCH")
+
+	// the set is compared, not the order
+	w = serve(t, Config{OmitDefaultMethodsHeader: true, Methods: []string{"PATCH", "HEAD", "OPTIONS", "DELETE", "PUT", "POST", "GET"}}, r)
+	expectHeader(t, w.header, headerAllowMethods, "*")
+	// a narrower list is emitted in full
+	w = serve(t, Config{OmitDefaultMethodsHeader: true, Methods: []string{"GET", "PATCH"}}, r)
+	expectHeader(t, w.header, headerAllowMethods, "GET, PATCH")
+}
+
+func TestPreflightCacheControl(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, PreflightCacheControl: "public, max-age=600"})
+
+	w := do(m, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerCacheControl, "public, max-age=600")
+	expectHeader(t, w.header, headerMaxAge, "3600")
+	// neither on simple requests nor rejected preflights
+	w = do(m, request("GET", "/", headerOrigin, "https://app.com"))
+	expectHeader(t, w.header, headerCacheControl, "")
+	w = do(m, preflightRequest("/", "https://app.com", "PURGE", ""))
+	expectHeader(t, w.header, headerCacheControl, "")
+
+	w = serve(t, Config{}, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerCacheControl, "")
+}
+
+func TestPassPreflightWhen(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{"https://app.com"},
+		PassPreflightWhen: func(ctx *rest.Context) bool {
+			return ctx.Request.Header.Get("Authorization") != ""
+		},
+	})
+
+	r := preflightRequest("/", "https://app.com", "PUT", "")
+	r.Header.Set("Authorization", "Bearer t0ken")
+	w := do(m, r)
+	if w.err != nil || w.ended || w.status != 0 {
+		t.Errorf("authenticated: err = %v, ended = %v, status = %d", w.err, w.ended, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
+
+	w = do(m, preflightRequest("/", "https://app.com", "PUT", ""))
+	if !w.ended || w.status != 204 {
+		t.Errorf("anonymous: ended = %v, status = %d", w.ended, w.status)
+	}
+	// rejections are never passed on
+	r = preflightRequest("/", "https://app.com", "PURGE", "")
+	r.Header.Set("Authorization", "Bearer t0ken")
+	if w = do(m, r); w.err != MethodNotAllowed {
+		t.Errorf("rejected: err = %v", w.err)
+	}
+}
+
+func TestAllowRequestedHeaders(t *testing.T) {
+	m := mustLoad(t, Config{Headers: []string{"Content-Type"}, AllowRequestedHeaders: true})
+
+	w := do(m, preflightRequest("/", "https://app.com", "PUT", "x-anything,  x-trace-id ,authorization"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "x-anything, x-trace-id, authorization")
+
+	// nothing requested, nothing echoed
+	w = do(m, preflightRequest("/", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowHeaders, "")
+	// only well-formed names are echoed
+	if w = do(m, preflightRequest("/", "https://app.com", "PUT", "x-a\r\nx-b: 1")); w.err != MalformedPreflight {
+		t.Errorf("malformed: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "")
+}
@@ -0,0 +1,52 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+/**
+ * Open preset: any origin, method and header, without credentials
+ */
+func AllowAll() Config {
+	return Config{
+		Origin:  []string{"*"},
+		Methods: copySlice(_config.Methods),
+		Headers: []string{"*"},
+	}
+}
+
+/**
+ * Credentialed preset for the listed origins, with any method and header
+ *
+ * Panics without origins or with `*`, credentials are never granted to every origin.
+ */
+func AllowAllWithCredentials(origins ...string) Config {
+	mustListOrigins(origins)
+	return Config{
+		Origin:      copySlice(origins),
+		Methods:     copySlice(_config.Methods),
+		Headers:     []string{"*"},
+		Credentials: true,
+	}
+}
+
+/**
+ * Restricted preset: only the listed origins, methods and headers, without credentials
+ *
+ * Panics without origins or with `*`.
+ */
+func RestrictedAPI(origins []string, methods []string, headers []string) Config {
+	mustListOrigins(origins)
+	return Config{
+		Origin:  copySlice(origins),
+		Methods: append([]string{}, methods...),
+		Headers: append([]string{}, headers...),
+	}
+}
+
+func mustListOrigins(origins []string) {
+	if len(origins) == 0 || hasMatch(origins, "*") {
+		panic("cors: preset requires explicitly listed origins")
+	}
+}
@@ -0,0 +1,74 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+)
+
+func TestAllowAll(t *testing.T) {
+	m := mustLoad(t, AllowAll())
+	w := do(m, preflightRequest("/", "https://any.com", "PATCH", "X-Anything"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "*")
+	expectHeader(t, w.header, headerAllowHeaders, "*")
+	expectHeader(t, w.header, headerAllowCredentials, "")
+}
+
+func TestAllowAllWithCredentials(t *testing.T) {
+	m := mustLoad(t, AllowAllWithCredentials("https://app.com"))
+	w := do(m, preflightRequest("/", "https://app.com", "DELETE", "X-Anything"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+	// `*` is literal with credentials, the requested headers are reflected
+	expectHeader(t, w.header, headerAllowHeaders, "X-Anything")
+	if w = do(m, request("GET", "/", headerOrigin, "https://evil.com")); w.err != OriginNotAllowed {
+		t.Errorf("unlisted: err = %v", w.err)
+	}
+
+	for _, origins := range [][]string{nil, {"*"}, {"https://app.com", "*"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: no panic", origins)
+				}
+			}()
+			AllowAllWithCredentials(origins...)
+		}()
+	}
+}
+
+func TestRestrictedAPI(t *testing.T) {
+	methods := []string{"GET", "POST"}
+	config := RestrictedAPI([]string{"https://app.com"}, methods, []string{"Content-Type"})
+	methods[0] = "DELETE"
+	m := mustLoad(t, config)
+
+	w := do(m, preflightRequest("/", "https://app.com", "POST", "content-type"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST")
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type")
+	expectHeader(t, w.header, headerAllowCredentials, "")
+	if w = do(m, preflightRequest("/", "https://app.com", "DELETE", "")); w.err != MethodNotAllowed {
+		t.Errorf("method: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/", "https://app.com", "POST", "X-Other")); w.err != HeadersNotAllowed {
+		t.Errorf("headers: err = %v", w.err)
+	}
+
+	// nil lists allow nothing rather than the defaults
+	m = mustLoad(t, RestrictedAPI([]string{"https://app.com"}, nil, nil))
+	if w = do(m, preflightRequest("/", "https://app.com", "GET", "")); w.err != MethodNotAllowed {
+		t.Errorf("no methods: err = %v", w.err)
+	}
+}
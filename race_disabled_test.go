@@ -0,0 +1,11 @@
+//go:build !race
+
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+
+package cors
+
+const raceEnabled = false
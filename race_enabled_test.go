@@ -0,0 +1,12 @@
+//go:build race
+
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+
+package cors
+
+// the race detector allocates on its own, allocation budgets don't hold
+const raceEnabled = true
@@ -0,0 +1,66 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+type staticResolver map[string]bool
+
+func (r staticResolver) Allowed(origin string) (bool, error) {
+	return r[origin], nil
+}
+
+/**
+ * Shared middleware hammered from many goroutines, run with `go test -race`
+ */
+func TestConcurrentRequests(t *testing.T) {
+	origins := []string{"https://app.com", "https://*.example.com"}
+	config := Config{
+		Origin:          origins,
+		Credentials:     true,
+		OriginCacheSize: 8,
+		OriginPolicies:  []OriginPolicy{{Origin: []string{"https://api.example.com"}, Methods: []string{"GET"}}},
+	}
+	m := mustLoad(t, config)
+	resolved := mustLoad(t, Config{Origin: []string{}, OriginResolver: staticResolver{"https://remote.com": true}})
+	route := []string{"https://route.com"}
+	// the caller keeps using its slices, the middleware holds copies
+	origins[0] = "https://changed.com"
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				// more origins than cache entries, so entries are evicted concurrently
+				origin := fmt.Sprintf("https://s%d.example.com", (g+i)%32)
+				if w := do(m, request("GET", "/", headerOrigin, origin)); w.err != nil {
+					t.Errorf("%s: err = %v", origin, w.err)
+					return
+				}
+				if w := do(m, preflightRequest("/", "https://app.com", "PUT", "content-type")); w.status != 204 {
+					t.Errorf("preflight: status = %d", w.status)
+					return
+				}
+				if w := do(resolved, request("GET", "/", headerOrigin, "https://remote.com")); w.err != nil {
+					t.Errorf("resolver: err = %v", w.err)
+					return
+				}
+				ctx := &rest.Context{Request: request("GET", "/", headerOrigin, "https://route.com")}
+				ctx.Set(ContextOrigins, route)
+				m.respond(newRecorder(ctx.Request), ctx)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
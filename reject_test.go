@@ -0,0 +1,202 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	var api rest.API
+	api.Use(Load(Config{Origin: []string{"https://app.com"}, RejectReasonHeader: true}))
+	api.Get("/", func(ctx *rest.Context) { ctx.Text("ok") })
+	// the status is set before `Throw`, an error handler only writes the body
+	for _, err := range []error{OriginNotAllowed, MethodNotAllowed, MalformedPreflight} {
+		api.Exception(err.Error(), func(ctx *rest.Context) {
+			ctx.JSON(map[string]string{"code": ctx.GetError().Error()})
+		})
+	}
+
+	cases := []struct {
+		r      *http.Request
+		status int
+		body   string
+		reason string
+	}{
+		{request("GET", "/", headerOrigin, "https://evil.com"), 403, `{"code":"ORIGIN_NOT_ALLOWED"}`, "origin"},
+		{preflightRequest("/", "https://app.com", "PURGE", ""), 403, `{"code":"METHOD_NOT_ALLOWED"}`, "method"},
+		{preflightRequest("/", "https://app.com", "GET", ","), 400, `{"code":"MALFORMED_PREFLIGHT"}`, "malformed"},
+		{request("GET", "/", headerOrigin, "https://app.com"), 200, "ok", ""},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, c.r)
+		res := rec.Result()
+		if res.StatusCode != c.status || strings.TrimSpace(rec.Body.String()) != c.body {
+			t.Errorf("%s: %d %q", c.body, res.StatusCode, rec.Body.String())
+		}
+		expectHeader(t, res.Header, headerRejectReason, c.reason)
+	}
+}
+
+func TestSilentReject(t *testing.T) {
+	silent := mustLoad(t, Config{Origin: []string{"https://app.com"}, SilentReject: true})
+	for _, r := range []*http.Request{
+		request("GET", "/", headerOrigin, "https://evil.com"),
+		preflightRequest("/", "https://app.com", "PURGE", ""),
+	} {
+		w := do(silent, r)
+		if w.err != nil || w.status != 403 || !w.ended {
+			t.Errorf("%s: err = %v, status = %d, ended = %v", r.Method, w.err, w.status, w.ended)
+		}
+		for _, call := range w.calls {
+			if call == "throw" {
+				t.Errorf("%s: error middleware invoked", r.Method)
+			}
+		}
+	}
+
+	w := serve(t, Config{Origin: []string{"https://app.com"}}, request("GET", "/", headerOrigin, "https://evil.com"))
+	if w.err != OriginNotAllowed || w.ended {
+		t.Errorf("default: err = %v, ended = %v", w.err, w.ended)
+	}
+}
+
+func TestSoftReject(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, SoftReject: true, RejectReasonHeader: true})
+
+	w := do(m, request("GET", "/", headerOrigin, "https://evil.com"))
+	if w.err != nil || w.status != 0 || w.ended {
+		t.Errorf("err = %v, status = %d, ended = %v", w.err, w.status, w.ended)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "")
+	expectHeader(t, w.header, headerRejectReason, "")
+
+	// other rejections stand, and are explained
+	w = do(m, preflightRequest("/", "https://app.com", "PURGE", ""))
+	if w.err != MethodNotAllowed || w.status != 403 {
+		t.Errorf("method: err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, headerRejectReason, "method")
+}
+
+func TestEnforceMethodOnSimpleRequest(t *testing.T) {
+	config := Config{Methods: []string{"GET", "PUT"}, EnforceMethodOnSimpleRequest: true}
+	m := mustLoad(t, config)
+	cases := map[string]error{
+		"GET":    nil,
+		"PUT":    nil,
+		"DELETE": MethodNotAllowed,
+		// simple methods never need a preflight, they are allowed anyway
+		"POST": nil,
+	}
+	for method, want := range cases {
+		if w := do(m, request(method, "/", headerOrigin, "https://app.com")); w.err != want {
+			t.Errorf("%s: err = %v, want %v", method, w.err, want)
+		}
+	}
+
+	config.EnforceMethodOnSimpleRequest = false
+	if w := serve(t, config, request("DELETE", "/", headerOrigin, "https://app.com")); w.err != nil {
+		t.Errorf("off by default: err = %v", w.err)
+	}
+}
+
+func TestRequireOrigin(t *testing.T) {
+	m := mustLoad(t, Config{RequireOrigin: true, RejectReasonHeader: true})
+
+	w := do(m, request("GET", "/font.woff2", headerOrigin, "https://any.com"))
+	if w.err != nil {
+		t.Fatalf("with origin: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "*")
+
+	w = do(m, request("GET", "/font.woff2"))
+	if w.err != OriginRequired || w.status != 403 {
+		t.Errorf("without origin: err = %v, status = %d", w.err, w.status)
+	}
+	expectHeader(t, w.header, "X-Cors-Reason", "origin-required")
+
+	if w = serve(t, Config{}, request("GET", "/font.woff2")); w.err != nil {
+		t.Errorf("default: err = %v", w.err)
+	}
+}
+
+func TestRejectReasonHeader(t *testing.T) {
+	config := Config{Origin: []string{"https://app.com"}, Methods: []string{"GET", "PUT"}, RejectReasonHeader: true}
+	m := mustLoad(t, config)
+	for _, c := range []struct {
+		r      *http.Request
+		reason string
+	}{
+		{request("GET", "/", headerOrigin, "https://evil.com"), "origin"},
+		{preflightRequest("/", "https://app.com", "DELETE", ""), "method"},
+		{preflightRequest("/", "https://app.com", "PUT", "X-Secret"), "headers"},
+		{preflightRequest("/", "https://app.com", "PUT", ",,"), "malformed"},
+		// allowed requests carry none
+		{request("GET", "/", headerOrigin, "https://app.com"), ""},
+		{preflightRequest("/", "https://app.com", "PUT", ""), ""},
+	} {
+		w := do(m, c.r)
+		expectHeader(t, w.header, headerRejectReason, c.reason)
+	}
+
+	tls := mustLoad(t, Config{RequireTLS: true, RejectReasonHeader: true})
+	expectHeader(t, do(tls, request("GET", "/", headerOrigin, "https://app.com")).header, headerRejectReason, "insecure")
+
+	config.RejectReasonHeader = false
+	w := serve(t, config, request("GET", "/", headerOrigin, "https://evil.com"))
+	expectHeader(t, w.header, headerRejectReason, "")
+}
+
+func TestSimpleMethods(t *testing.T) {
+	m := mustLoad(t, Config{Methods: []string{"GET", "PUT"}, SimpleMethods: []string{"GET"}, EnforceMethodOnSimpleRequest: true})
+	cases := map[string]error{
+		"GET":  nil,
+		"PUT":  nil,
+		"POST": MethodNotAllowed,
+		"HEAD": MethodNotAllowed,
+	}
+	for method, want := range cases {
+		if w := do(m, request(method, "/", headerOrigin, "https://app.com")); w.err != want {
+			t.Errorf("%s: err = %v, want %v", method, w.err, want)
+		}
+	}
+
+	// an empty list enforces every method
+	m = mustLoad(t, Config{Methods: []string{"PUT"}, SimpleMethods: []string{}, EnforceMethodOnSimpleRequest: true})
+	if w := do(m, request("GET", "/", headerOrigin, "https://app.com")); w.err != MethodNotAllowed {
+		t.Errorf("none simple: err = %v", w.err)
+	}
+}
+
+func TestRequireTLS(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, RequireTLS: true})
+
+	if w := do(m, request("GET", "https://api.com/", headerOrigin, "https://app.com")); w.err != nil {
+		t.Errorf("tls: err = %v", w.err)
+	}
+	// whatever the origin
+	for _, origin := range []string{"https://app.com", "https://evil.com"} {
+		w := do(m, request("GET", "http://api.com/", headerOrigin, origin))
+		if w.err != InsecureRequest || w.status != 403 {
+			t.Errorf("plaintext from %s: err = %v, status = %d", origin, w.err, w.status)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "")
+	}
+	if w := do(m, preflightRequest("http://api.com/", "https://app.com", "PUT", "")); w.err != InsecureRequest {
+		t.Errorf("plaintext preflight: err = %v", w.err)
+	}
+	// only CORS requests
+	if w := do(m, request("GET", "http://api.com/")); w.err != nil {
+		t.Errorf("without origin: err = %v", w.err)
+	}
+}
@@ -0,0 +1,32 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+)
+
+/**
+ * Request a report-only policy would have rejected
+ */
+type Violation struct {
+	Origin string
+	Method string
+	Path   string
+	Err    error
+}
+
+func report(config Config, r *http.Request, err error) {
+	if config.ReportFunc == nil {
+		return
+	}
+	config.ReportFunc(Violation{
+		Origin: r.Header.Get(headerOrigin),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Err:    err,
+	})
+}
@@ -0,0 +1,52 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+)
+
+func TestReportOnly(t *testing.T) {
+	var reports []Violation
+	m := mustLoad(t, Config{
+		Origin:      []string{"https://app.example.com"},
+		Methods:     []string{"GET", "POST"},
+		Credentials: true,
+		ReportOnly:  true,
+		ReportFunc:  func(v Violation) { reports = append(reports, v) },
+	})
+
+	w := do(m, request("GET", "/items", headerOrigin, "https://evil.example.com"))
+	if w.err != nil || w.ended || w.status != 0 {
+		t.Errorf("simple: err = %v, ended = %v, status = %d", w.err, w.ended, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://evil.example.com")
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+	want := Violation{Origin: "https://evil.example.com", Method: "GET", Path: "/items", Err: OriginNotAllowed}
+	if len(reports) != 1 || reports[0] != want {
+		t.Errorf("reports = %+v, want %+v", reports, want)
+	}
+
+	reports = nil
+	w = do(m, preflightRequest("/items", "https://evil.example.com", "PUT", "X-Secret"))
+	if w.err != nil || !w.ended || w.status != 204 {
+		t.Errorf("preflight: err = %v, ended = %v, status = %d", w.err, w.ended, w.status)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://evil.example.com")
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST, PUT")
+	expectHeader(t, w.header, headerAllowHeaders, "X-Secret")
+	// only the first failed check is reported
+	if len(reports) != 1 || reports[0].Err != OriginNotAllowed {
+		t.Errorf("reports = %+v", reports)
+	}
+
+	reports = nil
+	w = do(m, preflightRequest("/items", "https://app.example.com", "POST", "Content-Type"))
+	if w.err != nil || w.status != 204 || len(reports) != 0 {
+		t.Errorf("allowed: err = %v, status = %d, reports = %+v", w.err, w.status, reports)
+	}
+	expectHeader(t, w.header, headerAllowMethods, "GET, POST")
+}
@@ -0,0 +1,108 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ * Remote allowlist, e.g. a central service of a large deployment
+ */
+type OriginResolver interface {
+	Allowed(origin string) (bool, error)
+}
+
+// decisions cached when `OriginCacheSize` leaves the resolver cache unbounded
+const resolverCacheSize = 1024
+
+/**
+ * Resolver with an in-process TTL cache, errors are never cached
+ *
+ * Denials are cached as well, so a flood of unknown origins does not reach the resolver;
+ * the cache is bounded either way.
+ */
+type cachedResolver struct {
+	resolver OriginResolver
+	ttl      time.Duration
+	size     int
+	failOpen bool
+
+	mu      sync.Mutex
+	entries map[string]resolved
+}
+
+type resolved struct {
+	allowed bool
+	expires time.Time
+}
+
+func newCachedResolver(config Config) *cachedResolver {
+	if config.OriginResolver == nil {
+		return nil
+	}
+	size := config.OriginCacheSize
+	if size <= 0 {
+		size = resolverCacheSize
+	}
+	return &cachedResolver{
+		resolver: config.OriginResolver,
+		ttl:      config.ResolverTTL,
+		size:     size,
+		failOpen: config.ResolverFailOpen,
+		entries:  make(map[string]resolved),
+	}
+}
+
+/**
+ * Ask the resolver unless a fresh decision is cached, fail closed on error unless configured
+ */
+func (c *cachedResolver) allowed(origin string) bool {
+	if c == nil {
+		return false
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.entries[origin]
+	if ok && !now.Before(e.expires) {
+		delete(c.entries, origin)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return e.allowed
+	}
+
+	allowed, err := c.resolver.Allowed(origin)
+	if err != nil {
+		return c.failOpen
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= c.size {
+		c.prune(now)
+	}
+	// still full of fresh decisions, drop them all at once, they are cheap to resolve again
+	if len(c.entries) >= c.size {
+		c.entries = make(map[string]resolved)
+	}
+	c.entries[origin] = resolved{allowed: allowed, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return allowed
+}
+
+/**
+ * Evict expired decisions, the caller holds the lock
+ */
+func (c *cachedResolver) prune(now time.Time) {
+	for origin, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, origin)
+		}
+	}
+}
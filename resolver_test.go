@@ -0,0 +1,117 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+/**
+ * Resolver counting its calls, failing while err is set
+ */
+type fakeResolver struct {
+	allowed map[string]bool
+	err     error
+	calls   int
+}
+
+func (r *fakeResolver) Allowed(origin string) (bool, error) {
+	r.calls++
+	return r.allowed[origin], r.err
+}
+
+func TestOriginResolver(t *testing.T) {
+	resolver := &fakeResolver{allowed: map[string]bool{"https://remote.com": true}}
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, OriginResolver: resolver})
+	get := func(origin string) *recorder {
+		return do(m, request("GET", "/", headerOrigin, origin))
+	}
+
+	if w := get("https://remote.com"); w.err != nil {
+		t.Fatalf("resolved: err = %v", w.err)
+	}
+	if w := get("https://other.com"); w.err != OriginNotAllowed {
+		t.Errorf("denied by resolver: err = %v", w.err)
+	}
+	// the matchers answer first
+	get("https://app.com")
+	if resolver.calls != 2 {
+		t.Errorf("%d calls, want 2", resolver.calls)
+	}
+
+	// decisions are cached until they expire
+	get("https://remote.com")
+	get("https://other.com")
+	if resolver.calls != 2 {
+		t.Errorf("cached: %d calls, want 2", resolver.calls)
+	}
+	for origin, e := range m.resolver.entries {
+		e.expires = time.Now().Add(-time.Second)
+		m.resolver.entries[origin] = e
+	}
+	get("https://remote.com")
+	if resolver.calls != 3 {
+		t.Errorf("expired: %d calls, want 3", resolver.calls)
+	}
+}
+
+func TestOriginResolverBound(t *testing.T) {
+	resolver := &fakeResolver{allowed: map[string]bool{}}
+	m := mustLoad(t, Config{Origin: []string{}, OriginResolver: resolver, OriginCacheSize: -1})
+	for i := 0; i < 2*resolverCacheSize; i++ {
+		do(m, request("GET", "/", headerOrigin, "https://"+strconv.Itoa(i)+".evil.com"))
+	}
+	if n := len(m.resolver.entries); n == 0 || n > resolverCacheSize {
+		t.Errorf("unset size: %d entries, want at most %d", n, resolverCacheSize)
+	}
+
+	m = mustLoad(t, Config{Origin: []string{}, OriginResolver: resolver, OriginCacheSize: 3})
+	for _, origin := range []string{"https://a.com", "https://b.com", "https://c.com"} {
+		do(m, request("GET", "/", headerOrigin, origin))
+	}
+	e := m.resolver.entries["https://a.com"]
+	e.expires = time.Now().Add(-time.Second)
+	m.resolver.entries["https://a.com"] = e
+
+	// expired decisions make room first, fresh ones stay
+	do(m, request("GET", "/", headerOrigin, "https://d.com"))
+	if _, ok := m.resolver.entries["https://a.com"]; ok || len(m.resolver.entries) != 3 {
+		t.Errorf("entries = %v", m.resolver.entries)
+	}
+	// an expired decision is dropped on lookup
+	e = m.resolver.entries["https://b.com"]
+	e.expires = time.Now().Add(-time.Second)
+	m.resolver.entries["https://b.com"] = e
+	resolver.err = errors.New("unavailable")
+	do(m, request("GET", "/", headerOrigin, "https://b.com"))
+	if _, ok := m.resolver.entries["https://b.com"]; ok {
+		t.Error("expired entry kept")
+	}
+}
+
+func TestOriginResolverErrors(t *testing.T) {
+	resolver := &fakeResolver{allowed: map[string]bool{"https://remote.com": true}, err: errors.New("unavailable")}
+	config := Config{Origin: []string{}, OriginResolver: resolver}
+
+	m := mustLoad(t, config)
+	if w := do(m, request("GET", "/", headerOrigin, "https://remote.com")); w.err != OriginNotAllowed {
+		t.Errorf("fail closed: err = %v", w.err)
+	}
+	// errors are not cached
+	resolver.err = nil
+	if w := do(m, request("GET", "/", headerOrigin, "https://remote.com")); w.err != nil {
+		t.Errorf("recovered: err = %v", w.err)
+	}
+
+	resolver.err = errors.New("unavailable")
+	config.ResolverFailOpen = true
+	if w := serve(t, config, request("GET", "/", headerOrigin, "https://any.com")); w.err != nil {
+		t.Errorf("fail open: err = %v", w.err)
+	}
+}
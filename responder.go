@@ -0,0 +1,146 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+// Breaks the build here, rather than somewhere in the middleware, when the framework
+// changes its handler signature or the context methods used by the adapter
+var (
+	_ rest.Handler = (*Middleware)(nil).serve
+	_ Responder    = contextResponder{}
+)
+
+/**
+ * The part of a response CORS writes to
+ *
+ * `Handler` adapts `*rest.Context` to it, a lightweight fake is enough for tests.
+ */
+type Responder interface {
+	Request() *http.Request
+	SetHeader(key string, val string)
+	AddHeader(key string, val string)
+	Status(code int)
+	Throw(err error)
+	Text(data string)
+	End()
+}
+
+/**
+ * Responder which can tell the status set by earlier handlers, 0 if none, see `PreserveStatus`
+ *
+ * `*rest.Context` can't tell it, so only a custom responder passed to `Respond` implements it.
+ */
+type StatusResponder interface {
+	Responder
+	StatusCode() int
+}
+
+/**
+ * Adapter of `*rest.Context`, the only place depending on its response methods
+ */
+type contextResponder struct {
+	ctx *rest.Context
+}
+
+func (w contextResponder) Request() *http.Request {
+	return w.ctx.Request
+}
+
+func (w contextResponder) SetHeader(key string, val string) {
+	w.ctx.SetHeader(key, val)
+}
+
+func (w contextResponder) AddHeader(key string, val string) {
+	w.ctx.Response.Header().Add(key, val)
+}
+
+func (w contextResponder) Status(code int) {
+	w.ctx.Status(code)
+}
+
+func (w contextResponder) Throw(err error) {
+	w.ctx.Throw(err)
+}
+
+func (w contextResponder) Text(data string) {
+	w.ctx.Text(data)
+}
+
+func (w contextResponder) End() {
+	w.ctx.End()
+}
+
+/**
+ * Framework entry point, wraps the response writer when expose headers are negotiated
+ */
+func (m *Middleware) serve(ctx *rest.Context) {
+	if ctx == nil || ctx.Request == nil {
+		panic("cors: handler called without context or request")
+	}
+	res := m.respond(contextResponder{ctx}, ctx)
+	if res.negotiate && ctx.Response != nil {
+		ctx.Response = &exposeWriter{ResponseWriter: ctx.Response}
+	}
+}
+
+/**
+ * Write result to response, rejections go through the error middleware unless silent
+ *
+ * All headers are set before the status and the empty preflight body, nothing is written
+ * afterwards, so no header can land after the header block or in HTTP/2 trailers.
+ */
+func apply(w Responder, config Config, res Result, err error) {
+	for name, values := range res.Headers {
+		if len(values) == 1 {
+			w.SetHeader(name, values[0])
+			continue
+		}
+		for _, v := range values {
+			w.AddHeader(name, v)
+		}
+	}
+
+	if err != nil && !config.SilentReject {
+		w.Status(res.Status)
+		w.Throw(err)
+		return
+	}
+
+	if res.End {
+		// a successful preflight keeps the status set upstream, if there is one
+		if !(config.PreserveStatus && err == nil && upstreamStatus(w) != 0) {
+			w.Status(res.Status)
+		}
+		w.Text("")
+		w.End()
+	}
+}
+
+func upstreamStatus(w Responder) int {
+	if s, ok := w.(StatusResponder); ok {
+		return s.StatusCode()
+	}
+	return 0
+}
+
+/**
+ * Handle the request of a responder. Functions of the config receive a context
+ * holding only the request.
+ */
+func (m *Middleware) Respond(w Responder) {
+	m.respond(w, &rest.Context{Request: w.Request()})
+}
+
+func (m *Middleware) respond(w Responder, ctx *rest.Context) Result {
+	res, err := m.evaluate(ctx)
+	apply(w, m.config, res, err)
+	return res
+}
@@ -0,0 +1,91 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+/**
+ * Recorder telling the status set so far, like a framework context
+ */
+type statusRecorder struct {
+	*recorder
+}
+
+func (w statusRecorder) StatusCode() int {
+	return w.status
+}
+
+func TestPreserveStatus(t *testing.T) {
+	m := mustLoad(t, Config{PreserveStatus: true})
+
+	w := statusRecorder{newRecorder(preflightRequest("/", "https://app.com", "GET", ""))}
+	w.status = 200
+	m.Respond(w)
+	if w.status != 200 || !w.ended {
+		t.Errorf("upstream status: status = %d, ended = %v", w.status, w.ended)
+	}
+
+	w = statusRecorder{newRecorder(preflightRequest("/", "https://app.com", "GET", ""))}
+	m.Respond(w)
+	if w.status != 204 {
+		t.Errorf("no upstream status: status = %d", w.status)
+	}
+
+	if r := do(m, preflightRequest("/", "https://app.com", "GET", "")); r.status != 204 {
+		t.Errorf("responder without status: status = %d", r.status)
+	}
+	if r := do(m, preflightRequest("/", "https://app.com", "PURGE", "")); r.status != 403 {
+		t.Errorf("rejection: status = %d", r.status)
+	}
+}
+
+func TestResponderCalls(t *testing.T) {
+	// consecutive header calls count as one, only their place matters
+	steps := func(calls []string) []string {
+		var out []string
+		for _, c := range calls {
+			if c != "header" || len(out) == 0 || out[len(out)-1] != "header" {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}})
+	cases := []struct {
+		name string
+		r    *http.Request
+		want []string
+	}{
+		{"simple", request("GET", "/", headerOrigin, "https://app.com"), []string{"header"}},
+		{"preflight", preflightRequest("/", "https://app.com", "PUT", ""), []string{"header", "status", "text", "end"}},
+		{"rejection", request("GET", "/", headerOrigin, "https://evil.com"), []string{"status", "throw"}},
+		{"same origin", request("GET", "/"), nil},
+	}
+	for _, c := range cases {
+		w := do(m, c.r)
+		if got := steps(w.calls); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: calls = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFrameworkHandler(t *testing.T) {
+	var h rest.Handler = Load(Config{Origin: []string{"https://app.com"}, ExposeHeaders: []string{"X-Total-Count"}})
+	rec := httptest.NewRecorder()
+	// as the router calls it, with the request and the response writer of the server
+	h(&rest.Context{Request: request("GET", "/items", headerOrigin, "https://app.com"), Response: rec})
+	expectHeader(t, rec.Header(), headerAllowOrigin, "https://app.com")
+	expectHeader(t, rec.Header(), headerExposeHeaders, "X-Total-Count")
+	expectHeader(t, rec.Header(), headerVary, headerOrigin)
+}
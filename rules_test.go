@@ -0,0 +1,285 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestRules(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{"https://admin.example.com"},
+		Rules: []Rule{
+			{PathPrefix: "/api/public", OriginPolicy: OriginPolicy{Origin: []string{"https://*.example.org"}, Methods: []string{"GET"}}},
+			{PathPrefix: "/api/upload", OriginPolicy: OriginPolicy{Headers: []string{"Content-Type", "X-Upload-Id"}}},
+		},
+	})
+
+	w := do(m, request("GET", "/api/public/items", headerOrigin, "https://shop.example.org"))
+	expectHeader(t, w.header, headerAllowOrigin, "https://shop.example.org")
+	if w = do(m, request("GET", "/api/public/items", headerOrigin, "https://admin.example.com")); w.err != OriginNotAllowed {
+		t.Errorf("rule origin replaces the global ones: err = %v", w.err)
+	}
+	if w = do(m, preflightRequest("/api/public/items", "https://shop.example.org", "PUT", "")); w.err != MethodNotAllowed {
+		t.Errorf("rule methods: err = %v", w.err)
+	}
+
+	// unset origin of a rule keeps the global matchers
+	w = do(m, preflightRequest("/api/upload", "https://admin.example.com", "PUT", "X-Upload-Id"))
+	if w.err != nil {
+		t.Fatalf("rule headers: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowHeaders, "Content-Type, X-Upload-Id")
+
+	if w = do(m, request("GET", "/other", headerOrigin, "https://shop.example.org")); w.err != OriginNotAllowed {
+		t.Errorf("path without rule: err = %v", w.err)
+	}
+}
+
+func TestScopedWildcardWithCredentials(t *testing.T) {
+	config := Config{
+		Origin:        []string{"https://admin.example.com"},
+		Credentials:   true,
+		Rules:         []Rule{{PathPrefix: "/public", OriginPolicy: OriginPolicy{Origin: []string{"*"}}}},
+		OriginsByHost: map[string][]string{"open.example.com": {"*"}},
+	}
+	m := mustLoad(t, config)
+
+	for _, r := range []*http.Request{
+		request("GET", "/public/a", headerOrigin, "https://evil.example.net"),
+		request("GET", "http://open.example.com/a", headerOrigin, "https://evil.example.net"),
+	} {
+		w := do(m, r)
+		if w.err != nil {
+			t.Fatalf("%s %s: err = %v", r.Host, r.URL.Path, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "*")
+		expectHeader(t, w.header, headerAllowCredentials, "")
+	}
+
+	// the global list keeps its credentials
+	w := do(m, request("GET", "/private", headerOrigin, "https://admin.example.com"))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+
+	ctx := &rest.Context{Request: request("GET", "/route", headerOrigin, "https://evil.example.net")}
+	ctx.Set(ContextOrigins, []string{"*"})
+	w = newRecorder(ctx.Request)
+	m.respond(w, ctx)
+	expectHeader(t, w.header, headerAllowCredentials, "")
+
+	config.UnsafeWildcardCredentials = WildcardCredentialsPanic
+	rules, hosts := config, config
+	rules.OriginsByHost, hosts.Rules = nil, nil
+	if err := rules.Validate(); err != WildcardWithCredentials {
+		t.Errorf("rule: err = %v", err)
+	}
+	if err := hosts.Validate(); err != WildcardWithCredentials {
+		t.Errorf("host: err = %v", err)
+	}
+
+	// route origins are only known at runtime, they are rejected instead
+	hosts.OriginsByHost = nil
+	m = mustLoad(t, hosts)
+	ctx = &rest.Context{Request: request("GET", "/route", headerOrigin, "https://evil.example.net")}
+	ctx.Set(ContextOrigins, []string{"*"})
+	w = newRecorder(ctx.Request)
+	m.respond(w, ctx)
+	if w.err != OriginNotAllowed {
+		t.Errorf("route origins: err = %v", w.err)
+	}
+}
+
+func TestContextOrigins(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}})
+	route := []string{"https://route.com"}
+	serveRoute := func(origin string, val interface{}) *recorder {
+		ctx := &rest.Context{Request: request("GET", "/route", headerOrigin, origin)}
+		ctx.Set(ContextOrigins, val)
+		w := newRecorder(ctx.Request)
+		m.respond(w, ctx)
+		return w
+	}
+
+	if w := serveRoute("https://route.com", route); w.err != nil {
+		t.Errorf("route origin: err = %v", w.err)
+	}
+	if w := serveRoute("https://app.com", route); w.err != OriginNotAllowed {
+		t.Errorf("global origin: err = %v", w.err)
+	}
+	if len(m.routes.items) != 1 {
+		t.Errorf("%d matchers compiled for one route", len(m.routes.items))
+	}
+
+	matcher, err := CompileMatcher(Config{Origin: []string{"https://*.route.com"}, DenyOrigin: []string{"https://evil.route.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := serveRoute("https://a.route.com", matcher); w.err != nil {
+		t.Errorf("matcher: err = %v", w.err)
+	}
+	if w := serveRoute("https://evil.route.com", matcher); w.err != OriginNotAllowed {
+		t.Errorf("denied by matcher: err = %v", w.err)
+	}
+	if w := serveRoute("https://route.com", []string{}); w.err != OriginNotAllowed {
+		t.Errorf("empty list: err = %v", w.err)
+	}
+}
+
+func TestSharedMatcher(t *testing.T) {
+	matcher, err := CompileMatcher(Config{Origin: []string{"https://*.example.com"}, DenyOrigin: []string{"https://evil.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := LoadWithMatcher(Config{}, matcher)
+	// the origins of config are ignored in favor of the matcher
+	private := LoadWithMatcher(Config{Origin: []string{"https://other.com"}, Credentials: true, AllowCredentialsForPatterns: true}, matcher)
+	serveWith := func(h rest.Handler, origin string) http.Header {
+		rec := httptest.NewRecorder()
+		h(&rest.Context{Request: request("GET", "/", headerOrigin, origin), Response: rec})
+		return rec.Header()
+	}
+
+	for _, h := range []rest.Handler{public, private} {
+		expectHeader(t, serveWith(h, "https://app.example.com"), headerAllowOrigin, "https://app.example.com")
+		expectHeader(t, serveWith(h, "https://evil.example.com"), headerAllowOrigin, "")
+		expectHeader(t, serveWith(h, "https://other.com"), headerAllowOrigin, "")
+	}
+	expectHeader(t, serveWith(public, "https://app.example.com"), headerAllowCredentials, "")
+	expectHeader(t, serveWith(private, "https://app.example.com"), headerAllowCredentials, "true")
+
+	if _, err := CompileMatcher(Config{ResourcePolicy: "nobody"}); err != InvalidResourcePolicy {
+		t.Errorf("invalid config: err = %v", err)
+	}
+
+	// validated against the matcher, not the default `*` origin of config
+	var buf bytes.Buffer
+	strict := Config{Credentials: true, UnsafeWildcardCredentials: WildcardCredentialsPanic, Logger: log.New(&buf, "", 0)}
+	LoadWithMatcher(strict, matcher)
+	if buf.Len() != 0 {
+		t.Errorf("logged %q", buf.String())
+	}
+	all, _ := CompileMatcher(Config{})
+	for want, m := range map[string]*OriginMatcher{
+		"cors: WILDCARD_WITH_CREDENTIALS":                           all,
+		"cors: LoadWithMatcher needs a matcher from CompileMatcher": nil,
+	} {
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); msg != want {
+					t.Errorf("panic = %q, want %q", msg, want)
+				}
+			}()
+			LoadWithMatcher(strict, m)
+		}()
+	}
+}
+
+func TestUnsafeWildcardCredentials(t *testing.T) {
+	config := Config{Credentials: true}
+	r := request("GET", "/", headerOrigin, "https://any.com")
+
+	// reflect by default
+	w := serve(t, config, r)
+	if w.err != nil {
+		t.Fatalf("reflect: err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://any.com")
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+	expectHeader(t, w.header, headerVary, headerOrigin)
+
+	config.UnsafeWildcardCredentials = WildcardCredentialsReject
+	m := mustLoad(t, config)
+	if w = do(m, r); w.err != OriginNotAllowed || w.status != 403 {
+		t.Errorf("reject: err = %v, status = %d", w.err, w.status)
+	}
+	if w = do(m, request("GET", "/")); w.err != nil {
+		t.Errorf("reject, same origin: err = %v", w.err)
+	}
+
+	config.UnsafeWildcardCredentials = WildcardCredentialsPanic
+	if _, err := New(config); err != WildcardWithCredentials {
+		t.Errorf("panic: err = %v", err)
+	}
+	func() {
+		defer func() {
+			if msg, _ := recover().(string); msg != "cors: WILDCARD_WITH_CREDENTIALS" {
+				t.Errorf("panic = %q", msg)
+			}
+		}()
+		Load(config)
+	}()
+	// without credentials `*` is safe in every mode
+	config.Credentials = false
+	if w = serve(t, config, r); w.header.Get(headerAllowOrigin) != "*" {
+		t.Errorf("no credentials: headers = %v", w.header)
+	}
+}
+
+func TestCredentialPaths(t *testing.T) {
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, Credentials: true, CredentialPaths: []string{"/account/", "/orders/*/receipt"}})
+	for path, want := range map[string]string{
+		"/account/settings":  "true",
+		"/orders/7/receipt":  "true",
+		"/orders/7/items":    "",
+		"/public/items":      "",
+		"/accounts":          "",
+		"/orders/7/receipts": "",
+	} {
+		w := do(m, request("GET", path, headerOrigin, "https://app.com"))
+		if w.err != nil {
+			t.Errorf("%s: err = %v", path, w.err)
+		}
+		expectHeader(t, w.header, headerAllowOrigin, "https://app.com")
+		if got := w.header.Get(headerAllowCredentials); got != want {
+			t.Errorf("%s: credentials = %q, want %q", path, got, want)
+		}
+	}
+	// the preflight of a credentialed request needs them as well
+	w := do(m, preflightRequest("/account/settings", "https://app.com", "PUT", ""))
+	expectHeader(t, w.header, headerAllowCredentials, "true")
+
+	if _, err := New(Config{CredentialPaths: []string{"/a/["}}); err != InvalidCredentialPath {
+		t.Errorf("invalid pattern: err = %v", err)
+	}
+}
+
+func TestOriginsByHost(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin: []string{"https://www.example.com"},
+		OriginsByHost: map[string][]string{
+			"api.shop.com":      {"https://shop.com"},
+			"API.Blog.com:8443": {"https://blog.com", "https://*.blog.com"},
+		},
+	})
+	for _, c := range []struct {
+		target string
+		origin string
+		err    error
+	}{
+		{"http://api.shop.com/", "https://shop.com", nil},
+		{"http://api.shop.com:8080/", "https://shop.com", nil},
+		{"http://api.shop.com/", "https://blog.com", OriginNotAllowed},
+		// the host list replaces the global one
+		{"http://api.shop.com/", "https://www.example.com", OriginNotAllowed},
+		{"https://api.blog.com:8443/", "https://news.blog.com", nil},
+		{"https://api.blog.com:8443/", "https://shop.com", OriginNotAllowed},
+		// a key with port is that host and port only
+		{"https://api.blog.com/", "https://news.blog.com", OriginNotAllowed},
+		// unknown hosts use the global list
+		{"http://other.com/", "https://www.example.com", nil},
+		{"http://other.com/", "https://shop.com", OriginNotAllowed},
+	} {
+		if w := do(m, request("GET", c.target, headerOrigin, c.origin)); w.err != c.err {
+			t.Errorf("%s to %s: err = %v, want %v", c.origin, c.target, w.err, c.err)
+		}
+	}
+}
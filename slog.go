@@ -0,0 +1,41 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+/**
+ * Emit a structured event for a CORS decision, if a structured logger is configured
+ *
+ * Rejections are logged at info level, everything else at debug level. The source names
+ * the rule which allowed the origin, see `MatchSource`.
+ */
+func logDecision(config Config, r *http.Request, res Result, err error) {
+	if config.StructuredLogger == nil {
+		return
+	}
+	origin := r.Header.Get(headerOrigin)
+
+	decision, reason, level := "allow", "", slog.LevelDebug
+	switch {
+	case err != nil:
+		decision, reason, level = "reject", err.Error(), slog.LevelInfo
+	case origin == "":
+		decision = "skip"
+	}
+
+	config.StructuredLogger.LogAttrs(r.Context(), level, "cors",
+		slog.String("origin", origin),
+		slog.String("method", r.Method),
+		slog.String("decision", decision),
+		slog.String("reason", reason),
+		slog.String("source", res.Source.String()),
+		slog.Int("status", res.Status),
+	)
+}
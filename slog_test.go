@@ -0,0 +1,63 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+/**
+ * Handler keeping the records with their attributes as strings
+ */
+type recordHandler struct {
+	records *[]map[string]string
+}
+
+func (h recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h recordHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := map[string]string{"level": r.Level.String(), "msg": r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	*h.records = append(*h.records, attrs)
+	return nil
+}
+
+func (h recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h recordHandler) WithGroup(string) slog.Handler { return h }
+
+func TestStructuredLogger(t *testing.T) {
+	var records []map[string]string
+	m := mustLoad(t, Config{Origin: []string{"https://app.com"}, StructuredLogger: slog.New(recordHandler{&records})})
+
+	do(m, request("GET", "/", headerOrigin, "https://evil.com"))
+	want := map[string]string{
+		"level": "INFO", "msg": "cors", "origin": "https://evil.com", "method": "GET",
+		"decision": "reject", "reason": "ORIGIN_NOT_ALLOWED", "source": "none", "status": "403",
+	}
+	if len(records) != 1 || !reflect.DeepEqual(records[0], want) {
+		t.Fatalf("records = %v, want %v", records, want)
+	}
+
+	records = nil
+	do(m, preflightRequest("/", "https://app.com", "PUT", ""))
+	do(m, request("GET", "/"))
+	if len(records) != 2 {
+		t.Fatalf("records = %v", records)
+	}
+	if r := records[0]; r["level"] != "DEBUG" || r["decision"] != "allow" || r["source"] != "exact" || r["status"] != "204" {
+		t.Errorf("allow: %v", r)
+	}
+	if r := records[1]; r["decision"] != "skip" || r["origin"] != "" {
+		t.Errorf("skip: %v", r)
+	}
+}
@@ -0,0 +1,52 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+/**
+ * Rule which allowed an origin, for auditing overly broad rules
+ */
+type MatchSource int
+
+const (
+	MatchNone MatchSource = iota
+	// `*` in `Origin`
+	MatchAll
+	// exact `Origin` entry
+	MatchExact
+	// `scheme://host:*` entry
+	MatchAnyPort
+	// `scheme://*.host` entry
+	MatchWildcard
+	// `OriginGlobs`, `OriginPatterns`, `OriginRegexes` and `AllowOriginFunc`
+	MatchGlob
+	MatchPattern
+	MatchRegex
+	MatchFunc
+	// `OriginResolver`, `AllowByClientCertSAN` and `TokenValidator`
+	MatchResolver
+	MatchClientCert
+	MatchToken
+	// `self` in `Origin`
+	MatchSelf
+)
+
+var matchSources = []string{
+	"none", "all", "exact", "any-port", "wildcard", "glob", "pattern", "regex", "func", "resolver", "client-cert", "token", "self",
+}
+
+func (s MatchSource) String() string {
+	if s < 0 || int(s) >= len(matchSources) {
+		return "unknown"
+	}
+	return matchSources[s]
+}
+
+/**
+ * Rule covering an open ended set of origins, any port of a host included
+ */
+func (s MatchSource) pattern() bool {
+	return s == MatchAnyPort || s == MatchWildcard || s == MatchGlob || s == MatchPattern || s == MatchRegex
+}
@@ -0,0 +1,47 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestMatchSource(t *testing.T) {
+	cases := []struct {
+		config Config
+		origin string
+		want   MatchSource
+		name   string
+	}{
+		{Config{}, "https://any.com", MatchAll, "all"},
+		{Config{Origin: []string{"https://app.com"}}, "https://app.com", MatchExact, "exact"},
+		{Config{Origin: []string{"https://*.app.com"}}, "https://a.app.com", MatchWildcard, "wildcard"},
+		{Config{Origin: []string{"self"}}, "http://example.com", MatchSelf, "self"},
+		{Config{Origin: []string{}, AllowOriginFunc: func(string) bool { return true }}, "https://any.com", MatchFunc, "func"},
+		{Config{Origin: []string{}, TokenValidator: func(*rest.Context, string) bool { return true }}, "https://any.com", MatchToken, "token"},
+		{Config{Origin: []string{"https://app.com"}}, "https://evil.com", MatchNone, "none"},
+	}
+	for _, c := range cases {
+		// httptest requests are served by example.com
+		res, _ := Evaluate(c.config, request("GET", "/", headerOrigin, c.origin))
+		if res.Source != c.want || res.Source.String() != c.name {
+			t.Errorf("%s: source = %v", c.origin, res.Source)
+		}
+	}
+
+	// the first matcher in order wins, the cache keeps its source
+	cached := mustLoad(t, Config{Origin: []string{"https://app.com", "https://*.com"}})
+	for i := 0; i < 2; i++ {
+		if res, _ := cached.Evaluate(request("GET", "/", headerOrigin, "https://app.com")); res.Source != MatchExact {
+			t.Errorf("run %d: source = %v", i, res.Source)
+		}
+	}
+	if s := MatchSource(99).String(); s != "unknown" {
+		t.Errorf("out of range: %q", s)
+	}
+}
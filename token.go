@@ -0,0 +1,32 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"crypto/subtle"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+/**
+ * Compare secrets in constant time
+ *
+ * Plain origin matching compares public values and needs no constant time comparison,
+ * use this for tokens and signatures only.
+ */
+func SecureCompare(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+/**
+ * `TokenValidator` allowing any origin which sends the shared token in the given header
+ */
+func HeaderTokenValidator(header string, token string) func(ctx *rest.Context, origin string) bool {
+	return func(ctx *rest.Context, origin string) bool {
+		v := ctx.Request.Header.Get(header)
+		return v != "" && SecureCompare(v, token)
+	}
+}
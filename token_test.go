@@ -0,0 +1,67 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+func TestTokenValidator(t *testing.T) {
+	m := mustLoad(t, Config{
+		Origin:      []string{"https://app.com"},
+		Credentials: true,
+		TokenValidator: func(ctx *rest.Context, origin string) bool {
+			return ctx.Request.Header.Get("X-Embed-Token") == "signed:"+origin
+		},
+	})
+
+	w := do(m, request("GET", "/embed", headerOrigin, "https://blog.com", "X-Embed-Token", "signed:https://blog.com"))
+	if w.err != nil {
+		t.Fatalf("err = %v", w.err)
+	}
+	expectHeader(t, w.header, headerAllowOrigin, "https://blog.com")
+	expectHeader(t, w.header, headerVary, headerOrigin)
+
+	for _, token := range []string{"", "signed:https://other.com"} {
+		if w = do(m, request("GET", "/embed", headerOrigin, "https://blog.com", "X-Embed-Token", token)); w.err != OriginNotAllowed {
+			t.Errorf("token %q: err = %v", token, w.err)
+		}
+	}
+	// a token allow is never cached, the next request without one is rejected
+	do(m, request("GET", "/embed", headerOrigin, "https://blog.com", "X-Embed-Token", "signed:https://blog.com"))
+	if w = do(m, request("GET", "/embed", headerOrigin, "https://blog.com")); w.err != OriginNotAllowed {
+		t.Errorf("after token: err = %v", w.err)
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want bool
+	}{
+		{"s3cret", "s3cret", true},
+		{"", "", true},
+		{"s3cret", "s3creT", false},
+		{"s3cret", "s3cret!", false},
+		{"", "s3cret", false},
+	} {
+		if got := SecureCompare(c.a, c.b); got != c.want {
+			t.Errorf("SecureCompare(%q, %q) = %v", c.a, c.b, got)
+		}
+	}
+
+	m := mustLoad(t, Config{Origin: []string{}, TokenValidator: HeaderTokenValidator("X-Embed-Token", "s3cret")})
+	if w := do(m, request("GET", "/", headerOrigin, "https://blog.com", "X-Embed-Token", "s3cret")); w.err != nil {
+		t.Errorf("valid token: err = %v", w.err)
+	}
+	for _, token := range []string{"", "s3cre", "S3CRET"} {
+		if w := do(m, request("GET", "/", headerOrigin, "https://blog.com", "X-Embed-Token", token)); w.err != OriginNotAllowed {
+			t.Errorf("token %q: err = %v", token, w.err)
+		}
+	}
+}
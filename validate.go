@@ -0,0 +1,144 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"log"
+	"path"
+	"strings"
+)
+
+// Reference to: https://fetch.spec.whatwg.org/#cross-origin-resource-policy-header
+var resourcePolicies = []string{"same-site", "same-origin", "cross-origin"}
+
+// Reference to: https://html.spec.whatwg.org/multipage/browsers.html#cross-origin-opener-policies
+var openerPolicies = []string{"unsafe-none", "same-origin-allow-popups", "same-origin", "noopener-allow-popups"}
+
+// Reference to: https://html.spec.whatwg.org/multipage/browsers.html#embedder-policy-value
+var embedderPolicies = []string{"unsafe-none", "require-corp", "credentialless"}
+
+// Reference to: https://fetch.spec.whatwg.org/#forbidden-request-header
+var forbiddenRequestHeaders = []string{
+	"accept-charset", "accept-encoding", "access-control-request-headers", "access-control-request-method",
+	"connection", "content-length", "cookie", "cookie2", "date", "dnt", "expect", "host", "keep-alive",
+	"origin", "referer", "set-cookie", "te", "trailer", "transfer-encoding", "upgrade", "via",
+}
+
+// Reference to: https://fetch.spec.whatwg.org/#cors-safelisted-response-header-name
+var safelistedResponseHeaders = []string{
+	"cache-control", "content-language", "content-length", "content-type", "expires", "last-modified", "pragma",
+}
+
+/**
+ * Validate config, `Load` panics on an invalid config
+ */
+func (c Config) Validate() error {
+	if c.ResourcePolicy != "" && !hasMatch(resourcePolicies, c.ResourcePolicy) {
+		return InvalidResourcePolicy
+	}
+	if c.OpenerPolicy != "" && !hasMatch(openerPolicies, c.OpenerPolicy) {
+		return InvalidOpenerPolicy
+	}
+	if c.EmbedderPolicy != "" && !hasMatch(embedderPolicies, c.EmbedderPolicy) {
+		return InvalidEmbedderPolicy
+	}
+	if c.Credentials && c.hasWildcardOrigin() && c.UnsafeWildcardCredentials == WildcardCredentialsPanic {
+		return WildcardWithCredentials
+	}
+	for _, p := range c.CredentialPaths {
+		if _, err := path.Match(p, ""); err != nil {
+			return InvalidCredentialPath
+		}
+	}
+	if c.SpecCompliant && len(forbiddenHeaders(c.Headers)) > 0 {
+		return ForbiddenHeader
+	}
+	return nil
+}
+
+/**
+ * Config mistakes which are not fatal, reported through the logger at `Load`
+ */
+func (c Config) warnings() []string {
+	var out []string
+	if c.Credentials && hasMatch(c.ExposeHeaders, "*") && len(c.ExposeHeadersList) == 0 && !c.NegotiateExposeHeaders {
+		out = append(out, "expose headers * is ignored by browsers with credentials, set ExposeHeadersList")
+	}
+	if c.ExposeSafelistedHeaders {
+		for _, h := range c.ExposeHeaders {
+			if hasMatch(safelistedResponseHeaders, strings.ToLower(h)) {
+				out = append(out, "expose header "+h+" is safelisted and exposed anyway")
+			}
+		}
+	}
+	// nil gets the default, an empty list is taken as none at all
+	if c.Origin != nil && len(c.Origin) == 0 && !c.hasOriginMatchers() {
+		out = append(out, "origin is empty, so every origin is rejected; leave it nil for the default")
+	}
+	if c.Methods != nil && len(c.Methods) == 0 {
+		out = append(out, "methods is empty, so every preflight is rejected; leave it nil for the default")
+	}
+	if c.Headers != nil && len(c.Headers) == 0 {
+		out = append(out, "headers is empty, so preflights requesting Content-Type are rejected; leave it nil for the default")
+	}
+	for _, h := range forbiddenHeaders(c.Headers) {
+		out = append(out, "header "+h+" is forbidden, browsers never send it in Access-Control-Request-Headers")
+	}
+	return out
+}
+
+/**
+ * `*` entry in `Origin`, the `Origin` of a rule or an `OriginsByHost` list
+ */
+func (c Config) hasWildcardOrigin() bool {
+	if hasMatch(c.Origin, "*") {
+		return true
+	}
+	for _, rl := range c.Rules {
+		if hasMatch(rl.Origin, "*") {
+			return true
+		}
+	}
+	for _, list := range c.OriginsByHost {
+		if hasMatch(list, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Origins may be allowed otherwise than by `Origin`
+ */
+func (c Config) hasOriginMatchers() bool {
+	return len(c.OriginGlobs) > 0 || len(c.OriginPatterns) > 0 || len(c.OriginRegexes) > 0 ||
+		c.AllowOriginFunc != nil || c.OriginResolver != nil || c.TokenValidator != nil || c.AllowByClientCertSAN
+}
+
+func warn(config Config, msg string) {
+	switch {
+	case config.Logger != nil:
+		config.Logger.Print("cors: " + msg)
+	case config.StructuredLogger != nil:
+		config.StructuredLogger.Warn("cors: " + msg)
+	default:
+		log.Print("cors: " + msg)
+	}
+}
+
+/**
+ * Forbidden request header names found in the list
+ */
+func forbiddenHeaders(headers []string) []string {
+	var out []string
+	for _, h := range headers {
+		name := strings.ToLower(h)
+		if hasMatch(forbiddenRequestHeaders, name) || strings.HasPrefix(name, "proxy-") || strings.HasPrefix(name, "sec-") {
+			out = append(out, h)
+		}
+	}
+	return out
+}
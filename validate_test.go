@@ -0,0 +1,131 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"testing"
+)
+
+func TestResourcePolicy(t *testing.T) {
+	for _, policy := range []string{"same-site", "same-origin", "cross-origin"} {
+		// every handled response carries it, with or without origin
+		for _, r := range []*http.Request{request("GET", "/img.png"), request("GET", "/img.png", headerOrigin, "https://app.com")} {
+			w := serve(t, Config{ResourcePolicy: policy}, r)
+			expectHeader(t, w.header, headerResourcePolicy, policy)
+		}
+	}
+	if err := (Config{ResourcePolicy: "cross-site"}).Validate(); err != InvalidResourcePolicy {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := New(Config{ResourcePolicy: "cross-site"}); err != InvalidResourcePolicy {
+		t.Errorf("New: err = %v", err)
+	}
+}
+
+func TestIsolationPolicies(t *testing.T) {
+	w := serve(t, Config{OpenerPolicy: "same-origin", EmbedderPolicy: "require-corp"}, request("GET", "/"))
+	expectHeader(t, w.header, headerOpenerPolicy, "same-origin")
+	expectHeader(t, w.header, headerEmbedderPolicy, "require-corp")
+
+	w = serve(t, Config{}, request("GET", "/"))
+	if len(w.header) != 0 {
+		t.Errorf("off by default: %v", w.header)
+	}
+
+	if err := (Config{OpenerPolicy: "same-site"}).Validate(); err != InvalidOpenerPolicy {
+		t.Errorf("opener: err = %v", err)
+	}
+	if err := (Config{EmbedderPolicy: "require-cors"}).Validate(); err != InvalidEmbedderPolicy {
+		t.Errorf("embedder: err = %v", err)
+	}
+	if err := (Config{OpenerPolicy: "noopener-allow-popups", EmbedderPolicy: "credentialless"}).Validate(); err != nil {
+		t.Errorf("valid: err = %v", err)
+	}
+}
+
+func TestForbiddenHeaderWarning(t *testing.T) {
+	var buf bytes.Buffer
+	config := Config{Headers: []string{"Content-Type", "Origin", "Sec-Fetch-Mode"}, Logger: log.New(&buf, "", 0)}
+	if _, err := New(config); err != nil {
+		t.Fatal(err)
+	}
+	want := "cors: header Origin is forbidden, browsers never send it in Access-Control-Request-Headers\n" +
+		"cors: header Sec-Fetch-Mode is forbidden, browsers never send it in Access-Control-Request-Headers\n"
+	if buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+
+	config.SpecCompliant = true
+	if err := config.Validate(); err != ForbiddenHeader {
+		t.Errorf("spec compliant: err = %v", err)
+	}
+}
+
+func TestEmptyHeadersWarning(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+	r := preflightRequest("/", "https://app.com", "POST", "content-type")
+
+	// nil gets the default, which allows Content-Type
+	w := do(mustLoad(t, Config{Logger: logger}), r)
+	if w.err != nil || buf.Len() != 0 {
+		t.Errorf("nil: err = %v, logged %q", w.err, buf.String())
+	}
+
+	w = do(mustLoad(t, Config{Headers: []string{}, Logger: logger}), r)
+	if w.err != HeadersNotAllowed {
+		t.Errorf("empty: err = %v", w.err)
+	}
+	if want := "cors: headers is empty, so preflights requesting Content-Type are rejected; leave it nil for the default\n"; buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNilAndEmptyLists(t *testing.T) {
+	origin := func(config Config) error {
+		return serve(t, config, request("GET", "/", headerOrigin, "https://app.com")).err
+	}
+	preflight := func(config Config) error {
+		return serve(t, config, preflightRequest("/", "https://app.com", "PUT", "X-Request-Id")).err
+	}
+	for _, c := range []struct {
+		name   string
+		config Config
+		check  func(Config) error
+		want   error
+	}{
+		// nil takes the default, empty allows nothing, a list allows what it holds
+		{"nil origin", Config{}, origin, nil},
+		{"empty origin", Config{Origin: []string{}}, origin, OriginNotAllowed},
+		{"origin", Config{Origin: []string{"https://app.com"}}, origin, nil},
+		{"nil methods", Config{Headers: []string{"X-Request-Id"}}, preflight, nil},
+		{"empty methods", Config{Methods: []string{}, Headers: []string{"X-Request-Id"}}, preflight, MethodNotAllowed},
+		{"methods", Config{Methods: []string{"PUT"}, Headers: []string{"X-Request-Id"}}, preflight, nil},
+		{"nil headers", Config{}, preflight, HeadersNotAllowed},
+		{"empty headers", Config{Headers: []string{}}, preflight, HeadersNotAllowed},
+		{"headers", Config{Headers: []string{"X-Request-Id"}}, preflight, nil},
+	} {
+		if err := c.check(c.config); err != c.want {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// an empty origin list with another matcher is no mistake
+	var buf bytes.Buffer
+	New(Config{Origin: []string{}, OriginGlobs: []string{"https://*.app.com"}, Logger: log.New(&buf, "", 0)})
+	if buf.Len() != 0 {
+		t.Errorf("logged %q", buf.String())
+	}
+	New(Config{Origin: []string{}, Methods: []string{}, Logger: log.New(&buf, "", 0)})
+	want := "cors: origin is empty, so every origin is rejected; leave it nil for the default\n" +
+		"cors: methods is empty, so every preflight is rejected; leave it nil for the default\n"
+	if buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,35 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"net/http"
+
+	"github.com/go-rs/rest-api-framework"
+)
+
+/**
+ * Origin check for WebSocket upgrades, e.g. gorilla/websocket's `Upgrader.CheckOrigin`
+ *
+ * The origin is decided like for any CORS request. Browsers always send `Origin` on an
+ * upgrade, so a request without it comes from a non-browser client and is allowed as by
+ * the upgrader's default, unless `RequireOrigin` is set. Paths outside `PathPrefixes` are
+ * allowed, and so is everything under `ReportOnly`. Panics on an invalid config.
+ */
+func CheckOrigin(config Config) func(r *http.Request) bool {
+	m := mustNew(config, modeSimple)
+	return func(r *http.Request) bool {
+		if len(m.config.PathPrefixes) > 0 && !hasPrefix(m.config.PathPrefixes, r.URL.Path) {
+			return true
+		}
+		if r.Header.Get(headerOrigin) == "" && !m.config.RequireOrigin {
+			return true
+		}
+		// a report-only violation is reported by now, a soft rejection carries no error but no allowed origin either
+		res, err := m.evaluate(&rest.Context{Request: r})
+		return err == nil && (res.Source != MatchNone || res.violation != nil)
+	}
+}
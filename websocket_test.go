@@ -0,0 +1,46 @@
+/*!
+ * go-rs/cors
+ * Copyright(c) 2019 Roshan Gade
+ * MIT Licensed
+ */
+package cors
+
+import (
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	var reports []Violation
+	configs := map[string]Config{
+		"default":     {Origin: []string{"https://app.com"}, PathPrefixes: []string{"/ws"}},
+		"require":     {Origin: []string{"https://app.com"}, RequireOrigin: true},
+		"soft":        {Origin: []string{"https://app.com"}, SoftReject: true},
+		"report only": {Origin: []string{"https://app.com"}, ReportOnly: true, ReportFunc: func(v Violation) { reports = append(reports, v) }},
+	}
+	cases := []struct {
+		config string
+		path   string
+		origin string
+		want   bool
+	}{
+		{"default", "/ws", "https://app.com", true},
+		{"default", "/ws", "https://evil.com", false},
+		{"default", "/ws", "", true},
+		{"default", "/other", "https://evil.com", true},
+		{"require", "/ws", "", false},
+		{"soft", "/ws", "https://evil.com", false},
+		{"report only", "/ws", "https://evil.com", true},
+	}
+	for _, c := range cases {
+		r := request("GET", c.path, "Connection", "Upgrade", "Upgrade", "websocket")
+		if c.origin != "" {
+			r.Header.Set(headerOrigin, c.origin)
+		}
+		if got := CheckOrigin(configs[c.config])(r); got != c.want {
+			t.Errorf("%s, %s from %q: %v, want %v", c.config, c.path, c.origin, got, c.want)
+		}
+	}
+	if len(reports) != 1 || reports[0].Err != OriginNotAllowed {
+		t.Errorf("reports = %+v", reports)
+	}
+}